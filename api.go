@@ -0,0 +1,578 @@
+package main
+
+import (
+	db "DB"
+	dice "Dice"
+	pages "Pages"
+	rules "Rules"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Prefix of every route in the json API
+const apiPrefix = "/api/v1/"
+
+//Helper struct for decoding login and register requests
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+//Adds the json API routes to the default mux
+func (s *server) registerAPI() {
+	http.HandleFunc(apiPrefix+"users", s.apiUsersHandler)
+	http.HandleFunc(apiPrefix+"session", s.apiSessionHandler)
+	http.HandleFunc(apiPrefix+"sheets", s.apiSheetsHandler)
+	http.HandleFunc(apiPrefix+"sheets/", s.apiSheetsHandler)
+	http.HandleFunc(apiPrefix+"roll", s.apiRollHandler)
+	http.HandleFunc(apiPrefix+"trash", s.apiTrashHandler)
+	http.HandleFunc(apiPrefix+"trash/", s.apiTrashHandler)
+}
+
+//Errors from the rules and the dice that mean the change can't be made to the sheet as it is
+var ruleErrors = []error{
+	rules.ErrMaxLevel, rules.ErrNotEnoughXP, rules.ErrBadHPChoice, rules.ErrBadClass,
+	rules.ErrDead, rules.ErrBadAmount, rules.ErrNotDying, rules.ErrNotDead, rules.ErrBadHPAction,
+	rules.ErrNoHitDice, rules.ErrBadRest, rules.ErrBadCondition,
+	rules.ErrBadCoins, rules.ErrNotEnoughCoins, rules.ErrBadCoinAction,
+	rules.ErrBadCheck, dice.ErrBadNotation, dice.ErrBadMode, ErrNoSheet, ErrBadName,
+}
+
+//Picks the status code that matches an error from the store or the rules
+func errorStatus(err error) int {
+	for _, ruleErr := range ruleErrors {
+		if errors.Is(err, ruleErr) {
+			return http.StatusUnprocessableEntity
+		}
+	}
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrExists), errors.Is(err, db.ErrInTrash):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrBadPatch):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+//Decodes a json request body, and writes a 400 response if it can't be read
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Could not read the request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+//Writes a 422 response listing what is wrong with a submitted sheet, by field
+func writeInvalid(w http.ResponseWriter, errs pages.FieldErrors) {
+	writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"message": "The sheet is not valid", "errors": errs})
+}
+
+//Brings a submitted sheet up to the current layout, computes its slots and resources, and checks it.
+//Sheets can still be sent in the older layout, like without a class list
+func prepareSheet(sheet *pages.Sheet) pages.FieldErrors {
+	sheet.Migrate()
+	rules.UpdateSlots(sheet)
+	errs := rules.UpdateResources(sheet)
+	for path, message := range sheet.Validate() {
+		errs.Add(path, message)
+	}
+	return errs
+}
+
+//Handler registers a new user. POST {"username":"..","password":".."}
+func (s *server) apiUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to register a user")
+		return
+	}
+	creds := credentials{}
+	if !decodeBody(w, r, &creds) {
+		return
+	}
+	if creds.Username == "" || creds.Password == "" {
+		writeMessage(w, http.StatusUnprocessableEntity, "Username and password are required")
+		return
+	}
+	exist, err := s.store.CheckUserName(creds.Username)
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	if exist {
+		writeMessage(w, http.StatusConflict, "Username is already taken")
+		return
+	}
+	user := db.User{Username: creds.Username, Password: makeHash([]byte(creds.Password))}
+	if err = s.store.RegisterUser(user); err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"username": user.Username})
+}
+
+//Handler logs a user in with POST {"username":"..","password":".."}, or out with DELETE
+func (s *server) apiSessionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		creds := credentials{}
+		if !decodeBody(w, r, &creds) {
+			return
+		}
+		ok, err := s.store.CheckUser(creds.Username, creds.Password)
+		if err != nil || !ok {
+			writeMessage(w, http.StatusUnauthorized, "Could not match password or username")
+			return
+		}
+		setSession(creds.Username, w)
+		writeJSON(w, http.StatusOK, map[string]string{"username": creds.Username})
+	case http.MethodDelete:
+		clearSession(w)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to log in and DELETE to log out")
+	}
+}
+
+//What can be done with a single sheet, after its ID in the path. The revisions have routes of their own
+var sheetActions = []string{"derived", "levelup", "hitpoints", "rest", "conditions", "coins", "roll", "rename", "clone"}
+
+//Handler for /api/v1/sheets and /api/v1/sheets/<id>. Sheets can also be referred to by their name, as they were before they had IDs
+func (s *server) apiSheetsHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	ref := strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix+"sheets"), "/")
+	if ref == "" {
+		s.apiSheetList(w, r, username)
+		return
+	}
+	action := ""
+	if i := strings.Index(ref, "/revisions"); i >= 0 {
+		ref, action = ref[:i], ref[i+1:]
+	} else {
+		for _, suffix := range sheetActions {
+			if strings.HasSuffix(ref, "/"+suffix) {
+				ref, action = strings.TrimSuffix(ref, "/"+suffix), suffix
+				break
+			}
+		}
+	}
+	name, err := s.sheetName(username, ref)
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	switch action {
+	case "":
+		s.apiSheet(w, r, username, name)
+	case "derived":
+		s.apiDerived(w, r, username, name)
+	case "levelup":
+		s.apiLevelUp(w, r, username, name)
+	case "hitpoints":
+		s.apiHitPoints(w, r, username, name)
+	case "rest":
+		s.apiRest(w, r, username, name)
+	case "conditions":
+		s.apiConditions(w, r, username, name)
+	case "coins":
+		s.apiCoins(w, r, username, name)
+	case "roll":
+		s.apiRoll(w, r, username, name)
+	case "rename":
+		s.apiRename(w, r, username, name)
+	case "clone":
+		s.apiClone(w, r, username, name)
+	default:
+		s.apiRevisions(w, r, username, name, strings.Trim(strings.TrimPrefix(action, "revisions"), "/"))
+	}
+}
+
+//Gives the values computed from one of the user's sheets
+func (s *server) apiDerived(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodGet {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use GET to get the derived values")
+		return
+	}
+	sheet, err := s.store.GetSheet(username, name)
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rules.Derive(sheet))
+}
+
+//Levels up one of the user's sheets. POST {"method":"average"}, {"method":"roll"} or {"method":"manual","value":7}
+func (s *server) apiLevelUp(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to level up")
+		return
+	}
+	choice := rules.HPChoice{}
+	if !decodeBody(w, r, &choice) {
+		return
+	}
+	record := pages.LevelRecord{}
+	sheet, err := s.store.ModifySheet(username, name, func(sheet *pages.Sheet) (err error) {
+		record, err = rules.LevelUp(sheet, choice, s.dice.Die, time.Now())
+		return err
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"levelUp": record, "sheet": sheet})
+}
+
+//Changes the hit points of one of the user's sheets. POST {"action":"damage","amount":7,"critical":false}
+func (s *server) apiHitPoints(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to change hit points")
+		return
+	}
+	action := rules.HPAction{}
+	if !decodeBody(w, r, &action) {
+		return
+	}
+	sheet, err := s.store.ModifySheet(username, name, func(sheet *pages.Sheet) error {
+		return rules.ApplyHP(sheet, action, s.dice.Die)
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, sheet)
+}
+
+//Takes a rest with one of the user's sheets. POST {"type":"long"} or {"type":"short","dice":[{"class":"Fighter","count":2}]}
+func (s *server) apiRest(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to take a rest")
+		return
+	}
+	rest := rules.Rest{}
+	if !decodeBody(w, r, &rest) {
+		return
+	}
+	event := pages.Event{}
+	sheet, err := s.store.ModifySheet(username, name, func(sheet *pages.Sheet) (err error) {
+		event, err = rules.TakeRest(sheet, rest, s.dice.Die, time.Now())
+		return err
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"rest": event, "sheet": sheet})
+}
+
+//Changes the conditions of one of the user's sheets. POST {"action":"add","condition":{"name":"Prone"}} or {"action":"exhaustion","amount":2}
+func (s *server) apiConditions(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to change conditions")
+		return
+	}
+	action := rules.ConditionAction{}
+	if !decodeBody(w, r, &action) {
+		return
+	}
+	sheet, err := s.store.ModifySheet(username, name, func(sheet *pages.Sheet) error {
+		return rules.ApplyCondition(sheet, action)
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, sheet)
+}
+
+//Changes the coins of one of the user's sheets. POST {"action":"spend","amount":"12 gp 5 sp","description":".."} or {"action":"consolidate"}
+func (s *server) apiCoins(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to change coins")
+		return
+	}
+	action := rules.CoinAction{}
+	if !decodeBody(w, r, &action) {
+		return
+	}
+	transaction := pages.Transaction{}
+	sheet, err := s.store.ModifySheet(username, name, func(sheet *pages.Sheet) (err error) {
+		transaction, err = rules.ApplyCoins(sheet, action, time.Now())
+		return err
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"transaction": transaction, "sheet": sheet})
+}
+
+//Handler rolls dice without a sheet. POST {"notation":"4d6kh3"} or {"notation":"1d20+5","mode":"advantage"}
+func (s *server) apiRollHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	s.apiRoll(w, r, username, "")
+}
+
+//Rolls dice for one of the user's sheets, and logs the roll on it. POST {"check":"save:Dexterity","mode":"disadvantage"} or {"notation":"2d6+3","label":".."}
+func (s *server) apiRoll(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to roll")
+		return
+	}
+	request := rollRequest{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	response, err := s.roll(username, name, request)
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+//Gives one of the user's sheets a new name, keeping its ID. POST {"name":".."}
+func (s *server) apiRename(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to rename a sheet")
+		return
+	}
+	var body struct { //Help struct that holds the decoded request
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	sheet, err := s.renameSheet(username, name, body.Name)
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, sheet)
+}
+
+//Copies one of the user's sheets under a new name. POST {"name":"..","resetExp":true,"resetHitPoints":true,"resetCoins":true,"resetInventory":true},
+//where the resets can be left out
+func (s *server) apiClone(w http.ResponseWriter, r *http.Request, username string, name string) {
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to clone a sheet")
+		return
+	}
+	options := pages.CloneOptions{}
+	if !decodeBody(w, r, &options) {
+		return
+	}
+	sheet, err := s.cloneSheet(username, name, options)
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, sheet)
+}
+
+//Handles the revisions of one of the user's sheets. GET revisions lists them, GET revisions/<version> gives one with its sheet,
+//GET revisions/diff?from=<version>&to=<version> compares two, or one with the current sheet if to is left out, and POST revisions/<version>/restore restores one
+func (s *server) apiRevisions(w http.ResponseWriter, r *http.Request, username string, name string, rest string) {
+	if (rest == "" || rest == "diff") && r.Method != http.MethodGet {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use GET to list and compare revisions")
+		return
+	}
+	if rest == "" {
+		revisions, err := s.store.ListRevisions(username, name)
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]pages.Revision{"revisions": revisions})
+		return
+	}
+	if rest == "diff" {
+		from, to, err := diffVersions(r)
+		if err != nil {
+			writeMessage(w, http.StatusBadRequest, "from and to have to be revision versions")
+			return
+		}
+		changes, err := s.diffRevisions(username, name, from, to)
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]pages.Change{"changes": changes})
+		return
+	}
+	parts := strings.Split(rest, "/")
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) > 2 || (len(parts) == 2 && parts[1] != "restore") {
+		writeMessage(w, http.StatusNotFound, "Unknown revisions route")
+		return
+	}
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			writeMessage(w, http.StatusMethodNotAllowed, "Use POST to restore a revision")
+			return
+		}
+		sheet, err := s.store.RestoreRevision(username, name, version)
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, sheet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use GET to get a revision")
+		return
+	}
+	revision, err := s.store.GetRevision(username, name, version)
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, revision)
+}
+
+//Handler for /api/v1/trash. GET lists the sheets in the trash, POST trash/<name>/restore takes one out and DELETE trash/<name> purges it
+func (s *server) apiTrashHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	ref := strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix+"trash"), "/")
+	var err error
+	switch {
+	case ref == "" && r.Method == http.MethodGet:
+		trash, err := s.trashedSheets(username)
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sheets": trash, "retentionDays": s.trashDays})
+		return
+	case strings.HasSuffix(ref, "/restore") && r.Method == http.MethodPost:
+		var name string
+		if name, err = s.trashedName(username, strings.TrimSuffix(ref, "/restore")); err == nil {
+			err = s.store.RestoreSheet(username, name)
+		}
+	case ref != "" && r.Method == http.MethodDelete:
+		var name string
+		if name, err = s.trashedName(username, ref); err == nil {
+			err = s.store.PurgeSheet(username, name)
+		}
+	default:
+		writeMessage(w, http.StatusMethodNotAllowed, "Use GET to list the trash, POST on trash/<id>/restore to restore a sheet and DELETE on trash/<id> to purge one")
+		return
+	}
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//Lists the user's sheets, or creates a new one
+func (s *server) apiSheetList(w http.ResponseWriter, r *http.Request, username string) {
+	switch r.Method {
+	case http.MethodGet:
+		sheets, err := s.store.GetSheets(username)
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]pages.SheetInfo{"sheets": sheets})
+	case http.MethodPost:
+		sheet := pages.Sheet{}
+		if !decodeBody(w, r, &sheet) {
+			return
+		}
+		if errs := prepareSheet(&sheet); len(errs) > 0 {
+			writeInvalid(w, errs)
+			return
+		}
+		if err := s.store.RegisterSheet(username, sheet); err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		sheet, err := s.store.GetSheet(username, sheet.Name) //Get the sheet back with its ID
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, sheet)
+	default:
+		writeMessage(w, http.StatusMethodNotAllowed, "Use GET to list sheets and POST to create one")
+	}
+}
+
+//Gets, replaces, patches or deletes one of the user's sheets. Deleted sheets go to the trash
+func (s *server) apiSheet(w http.ResponseWriter, r *http.Request, username string, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		sheet, err := s.store.GetSheet(username, name)
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, sheet)
+	case http.MethodPut:
+		sheet := pages.Sheet{}
+		if !decodeBody(w, r, &sheet) {
+			return
+		}
+		if sheet.Name != "" && sheet.Name != name {
+			writeMessage(w, http.StatusUnprocessableEntity, "The sheet name can't be changed here, rename the sheet instead")
+			return
+		}
+		sheet.Name = name
+		sheet.Owner = username
+		if errs := prepareSheet(&sheet); len(errs) > 0 {
+			writeInvalid(w, errs)
+			return
+		}
+		sheet, err := s.store.ModifySheet(username, name, func(stored *pages.Sheet) error {
+			*stored = keepTracked(*stored, sheet)
+			return nil
+		})
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, sheet)
+	case http.MethodPatch:
+		var ops []db.SheetOp
+		if !decodeBody(w, r, &ops) {
+			return
+		}
+		sheet, err := s.store.PatchSheet(username, name, ops)
+		if err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, sheet)
+	case http.MethodDelete:
+		if err := s.store.DeleteSheet(username, name); err != nil {
+			writeMessage(w, errorStatus(err), err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMessage(w, http.StatusMethodNotAllowed, "Use GET, PUT, PATCH or DELETE on a sheet")
+	}
+}
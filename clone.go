@@ -0,0 +1,78 @@
+package main
+
+import (
+	db "DB"
+	pages "Pages"
+	rules "Rules"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+//Copies one of the user's sheets under the name in the options, resetting what the options pick
+func (s *server) cloneSheet(username string, name string, options pages.CloneOptions) (pages.Sheet, error) {
+	newName := strings.TrimSpace(options.Name)
+	if newName == "" {
+		return pages.Sheet{}, ErrBadName
+	}
+	return db.CloneSheet(s.store, username, name, newName, func(sheet *pages.Sheet) {
+		rules.ResetClone(sheet, options)
+	})
+}
+
+//Handler loads the clone page of a sheet, where the new name and what to reset are picked
+func (s *server) clonePageHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Routes back to index if accessed without being logged in yet
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	sheet, err := s.findSheet(username, r.FormValue("sheet"))
+	if err != nil { //Loads error page if we failed to load the character sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	page := pages.ClonePage{ID: sheet.ID, Name: sheet.Name, Options: pages.CloneOptions{Name: sheet.Name + " (copy)"}}
+	loadClonePage(w, http.StatusOK, page)
+}
+
+//Handler clones a sheet from the clone page, and routes to the new sheet
+func (s *server) cloneHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	sheet, err := s.findSheet(username, r.FormValue("sheet"))
+	if err != nil { //Loads error page if we failed to load the character sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	page := pages.ClonePage{ID: sheet.ID, Name: sheet.Name, Options: pages.CloneOptions{
+		Name:           r.FormValue("name"),
+		ResetExp:       r.FormValue("resetExp") == "true",
+		ResetHitPoints: r.FormValue("resetHitPoints") == "true",
+		ResetCoins:     r.FormValue("resetCoins") == "true",
+		ResetInventory: r.FormValue("resetInventory") == "true",
+	}}
+	clone, err := s.cloneSheet(username, sheet.Name, page.Options)
+	if err != nil { //Show the clone page again with what went wrong
+		page.Error = "Could not clone the sheet: " + err.Error()
+		loadClonePage(w, errorStatus(err), page)
+		return
+	}
+	http.Redirect(w, r, "/sheet/?sheet="+url.QueryEscape(clone.ID), 303)
+}
+
+//Loads the clone page with the given data
+func loadClonePage(w http.ResponseWriter, status int, page pages.ClonePage) {
+	pageData, err := json.Marshal(page)
+	if err != nil {
+		panic(err)
+	}
+	t, _ := template.ParseFiles("./templates/clone.html")
+	w.WriteHeader(status)
+	t.Execute(w, string(pageData))
+}
@@ -3,11 +3,15 @@ module main
 go 1.15
 
 replace DB => ./mods/DB/
+replace Dice => ./mods/Dice/
 replace Pages => ./mods/Pages/
+replace Rules => ./mods/Rules/
 
 require (
 	DB v0.0.0-00010101000000-000000000000
+	Dice v0.0.0-00010101000000-000000000000
 	Pages v0.0.0-00010101000000-000000000000
+	Rules v0.0.0-00010101000000-000000000000
 	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.2.1
 	github.com/lib/pq v1.8.0 // indirect
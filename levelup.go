@@ -0,0 +1,93 @@
+package main
+
+import (
+	pages "Pages"
+	rules "Rules"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+//Handler loads the level-up page of a sheet
+func (s *server) levelUpPageHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Routes back to index if accessed without being logged in yet
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	sheet, err := s.findSheet(username, r.FormValue("sheet"))
+	if err != nil { //Loads error page if we failed to load the character sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	loadLevelUpPage(w, http.StatusOK, sheet, "")
+}
+
+//Loads the level-up page for the given sheet, showing what went wrong with the last attempt if anything
+func loadLevelUpPage(w http.ResponseWriter, status int, sheet pages.Sheet, message string) {
+	page := pages.LevelUpPage{
+		CharacterSheet: sheet,
+		NextLevelXP:    rules.NextLevelXP(sheet.Level),
+		ConModifier:    rules.Modifier(sheet.Scores.Constitution),
+		Error:          message,
+	}
+	if err := rules.CanLevelUp(sheet); err != nil {
+		page.Blocked = err.Error()
+	}
+	pageData, err := json.Marshal(page)
+	if err != nil {
+		panic(err)
+	}
+	t, _ := template.ParseFiles("./templates/levelUp.html")
+	w.WriteHeader(status)
+	t.Execute(w, string(pageData))
+}
+
+//Handler levels up a sheet with the hit point choice from the level-up form, and routes back to the sheet
+func (s *server) levelUpHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	name, err := s.sheetName(username, r.FormValue("sheet"))
+	if err != nil {
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	choice := rules.HPChoice{Method: r.FormValue("method"), Class: r.FormValue("class")}
+	if choice.Class == "" { //Multiclassing into the class typed into the form
+		choice.Class = r.FormValue("newClass")
+		choice.HitDie = r.FormValue("newHitDie")
+		if choice.Class == "" {
+			sheet, err := s.store.GetSheet(username, name)
+			if err != nil {
+				actionFailed(w, `{"message":"`+err.Error()+`"}`)
+			} else {
+				loadLevelUpPage(w, http.StatusUnprocessableEntity, sheet, "Type in the name of the new class")
+			}
+			return
+		}
+	}
+	if choice.Method == "manual" {
+		value, err := strconv.Atoi(r.FormValue("value"))
+		if err != nil {
+			value = 0 //Let LevelUp turn it down along with other bad values
+		}
+		choice.Value = value
+	}
+	sheet, err := s.store.ModifySheet(username, name, func(sheet *pages.Sheet) error {
+		_, err := rules.LevelUp(sheet, choice, s.dice.Die, time.Now())
+		return err
+	})
+	if err != nil && errorStatus(err) == http.StatusUnprocessableEntity { //Show the level-up page again with what was wrong
+		loadLevelUpPage(w, http.StatusUnprocessableEntity, sheet, err.Error())
+	} else if err != nil { //Load fail page if we fail to update the sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+	} else { //Route to the leveled up sheet if all is well
+		http.Redirect(w, r, "/sheet/?sheet="+url.QueryEscape(sheet.ID), 303)
+	}
+}
@@ -2,24 +2,33 @@ package main
 
 import (
 	db "DB"
+	dice "Dice"
 	pages "Pages"
+	rules "Rules"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
-	"strings"
+	"syscall"
 	"text/template"
+	"time"
 
 	"github.com/gorilla/securecookie"
 	"golang.org/x/crypto/bcrypt"
 )
 
-//Helper struct for parsing feats and allies in a submitted character sheet
-type featOrAlly struct {
-	Name        string //The name of the given object
-	Description string //Description of the object
+//server holds the dependencies shared by the handlers
+type server struct {
+	store     db.Store     //Storage backend for users and sheets
+	dice      *dice.Roller //Rolls every die the server rolls, like hit dice and the rolls on the sheet page
+	trashDays int          //How many days deleted sheets stay in the trash. 0 keeps them until purged by hand
 }
 
 //Sets the session cookie
@@ -28,27 +37,163 @@ var cookieHandler = securecookie.New(
 	securecookie.GenerateRandomKey(32))
 
 func main() {
+	repair := flag.Bool("repair", false, "fix the sheets left out of sync with their users by older versions, then exit")
+	flag.Parse()
+	if *repair {
+		if err := repairStore(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	addr, err := determineListenAddress()
 	if err != nil {
 		log.Fatal(err)
 	}
-	http.HandleFunc("/", indexHandler)
-	http.HandleFunc("/index/", indexHandler)
-	http.HandleFunc("/loginpage/", loginPageHandler)
-	http.HandleFunc("/login/", loginHandler)
-	http.HandleFunc("/logout/", logoutHandler)
-	http.HandleFunc("/sheet/", sheetHandler)
-	http.HandleFunc("/register/", registerHandler)
-	http.HandleFunc("/registerpage/", registerPageHandler)
-	http.HandleFunc("/newsheet/", newSheetHandler)
-	http.HandleFunc("/newsheetpage/", newSheetPageHandler)
-	http.HandleFunc("/delete/", deleteHandler)
-	http.HandleFunc("/deletepage/", deletePageHandler)
+	store, err := newStore(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	roller, err := newRoller()
+	if err != nil {
+		log.Fatal(err)
+	}
+	days, err := trashDays()
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv := &server{store: store, dice: roller, trashDays: days}
+	http.HandleFunc("/", srv.indexHandler)
+	http.HandleFunc("/index/", srv.indexHandler)
+	http.HandleFunc("/loginpage/", srv.loginPageHandler)
+	http.HandleFunc("/login/", srv.loginHandler)
+	http.HandleFunc("/logout/", srv.logoutHandler)
+	http.HandleFunc("/sheet/", srv.sheetHandler)
+	http.HandleFunc("/register/", srv.registerHandler)
+	http.HandleFunc("/registerpage/", srv.registerPageHandler)
+	http.HandleFunc("/newsheet/", srv.newSheetHandler)
+	http.HandleFunc("/newsheetpage/", srv.newSheetPageHandler)
+	http.HandleFunc("/editsheet/", srv.editSheetPageHandler)
+	http.HandleFunc("/updatesheet/", srv.updateSheetHandler)
+	http.HandleFunc("/patchsheet/", srv.patchSheetHandler)
+	http.HandleFunc("/hitpoints/", srv.hitPointsHandler)
+	http.HandleFunc("/rest/", srv.restHandler)
+	http.HandleFunc("/conditions/", srv.conditionsHandler)
+	http.HandleFunc("/coins/", srv.coinsHandler)
+	http.HandleFunc("/roll/", srv.rollHandler)
+	http.HandleFunc("/revisions/", srv.revisionsHandler)
+	http.HandleFunc("/restore/", srv.restoreHandler)
+	http.HandleFunc("/leveluppage/", srv.levelUpPageHandler)
+	http.HandleFunc("/levelup/", srv.levelUpHandler)
+	http.HandleFunc("/rename/", srv.renameHandler)
+	http.HandleFunc("/clonepage/", srv.clonePageHandler)
+	http.HandleFunc("/clone/", srv.cloneHandler)
+	http.HandleFunc("/delete/", srv.deleteHandler)
+	http.HandleFunc("/deletepage/", srv.deletePageHandler)
+	http.HandleFunc("/trash/", srv.trashHandler)
+	http.HandleFunc("/trash/restore/", srv.untrashHandler)
+	http.HandleFunc("/trash/purge/", srv.purgeHandler)
+	srv.registerAPI()
+	httpServer := &http.Server{Addr: addr}
+	done := make(chan struct{})
+	go func() { //Stop taking requests when the process is told to stop, and let the running ones finish
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Println(err)
+		}
+		close(done)
+	}()
+	stopPurge := make(chan struct{})
+	purgeStopped := make(chan struct{})
+	go func() {
+		srv.purgeTrash(stopPurge)
+		close(purgeStopped)
+	}()
 	log.Printf("Listening on %s...\n", addr)
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
 		panic(err)
 	}
+	<-done
+	close(stopPurge) //Let a running purge finish before the store goes away
+	<-purgeStopped
+	if err := store.Close(); err != nil {
+		log.Println(err)
+	}
+}
+
+//Picks the storage backend. Uses mongodb if $CONNECTION is set, and an in-memory store otherwise. When repairing, the
+//database isn't checked, and the indexes are left for the repair to build
+func newStore(repairing bool) (db.Store, error) {
+	connection := os.Getenv("CONNECTION")
+	if connection == "" {
+		log.Println("$CONNECTION not set, keeping users and sheets in memory")
+		return db.NewMemoryStore(), nil
+	}
+	config := db.MongoConfig{Connection: connection, Repairing: repairing} //Repair builds the indexes once the data fits them
+	if pool := os.Getenv("MONGO_POOL_SIZE"); pool != "" {
+		size, err := strconv.ParseUint(pool, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("$MONGO_POOL_SIZE: %v", err)
+		}
+		config.MaxPoolSize = size
+	}
+	var err error
+	if config.ConnectTimeout, err = durationEnv("MONGO_CONNECT_TIMEOUT"); err != nil {
+		return nil, err
+	}
+	if config.QueryTimeout, err = durationEnv("MONGO_QUERY_TIMEOUT"); err != nil {
+		return nil, err
+	}
+	return db.NewMongoStore(config)
+}
+
+//Runs the repair of the mongodb store, and logs what it fixed
+func repairStore() error {
+	store, err := newStore(true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	mongoStore, ok := store.(*db.MongoStore)
+	if !ok {
+		return fmt.Errorf("there is nothing to repair in memory, set $CONNECTION to repair a database")
+	}
+	report, err := mongoStore.Repair(rules.UpdateSlots)
+	if err != nil {
+		return err
+	}
+	log.Printf("Checked %d users and %d sheets\n", report.Users, report.Sheets)
+	for _, name := range report.Dangling {
+		log.Printf("Dropped %s, which was listed but had no sheet\n", name)
+	}
+	for _, name := range report.Trashed {
+		log.Printf("Moved %s to the trash, as it wasn't listed with its owner\n", name)
+	}
+	log.Printf("Removed the sheet lists of %d users\n", report.ListsDropped)
+	log.Printf("Gave %d sheets an ID\n", report.IDsAdded)
+	for _, rename := range report.Renamed {
+		log.Printf("Renamed %s, as an older sheet had the same name\n", rename)
+	}
+	log.Printf("Kept %d revisions by the ID of their sheet, and dropped %d whose sheet is gone\n", report.RevisionsKeyed, report.RevisionsDropped)
+	log.Printf("Stored the spell slots of %d sheets\n", report.SlotsStored)
+	return nil
+}
+
+//Reads a duration like "10s" from an environment variable. Gives 0 if the variable isn't set
+func durationEnv(name string) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("$%s: %v", name, err)
+	}
+	return d, nil
 }
 
 //Loads the fail page with a submitted failure message. Called whenever an operation fails
@@ -60,108 +205,40 @@ func actionFailed(w http.ResponseWriter, message string) {
 	t.Execute(w, fail)
 }
 
-//Takes in a list of feats or allies from a submitted sheet, and then parses them into structs to be put into an array
-func parseFeatsAndAllies(list []string) []featOrAlly {
-	//List is a an array of strings in the format "<name>:<description>"
-	featsOrAllies := []featOrAlly{}  //Array we will be returning
-	for i := 0; i < len(list); i++ { //Loop through each item in the list
-		feOAl := featOrAlly{}               //Struct we will be filling to data
-		info := strings.Split(list[i], ":") //Split the name and description apart
-		if len(info) == 2 {                 //Assuming the input was valid, we should have a length of 2
-			for j := 0; j < len(info); j++ { //Fill in the values of the struct
-				switch j {
-				case 0:
-					{
-						feOAl.Name = info[j]
-						break
-					}
-				case 1:
-					{
-						feOAl.Description = info[j]
-						break
-					}
-				}
-			}
-			featsOrAllies = append(featsOrAllies, feOAl) //Append to the array
-		}
-	}
-	return featsOrAllies
-}
-
-//Takes in a list of items from the submitted sheet, and then parses them into structs to be an inventory array
-func parseItems(itemList []string) []pages.Item {
-	//Each item in the list is a string in the format "<amount>:<name>:<description>"
-	inventory := []pages.Item{}          //Array we will be returning
-	for i := 0; i < len(itemList); i++ { //Looping through each tiem
-		item := pages.Item{}                        //Struct we're filling with data
-		itemInfo := strings.Split(itemList[i], ":") //Split up the amount, name and description
-		if len(itemInfo) == 3 {
-			for j := 0; j < len(itemInfo); j++ { //Put the data into the struct
-				switch j {
-				case 0:
-					{
-						num, err := strconv.Atoi(itemInfo[j]) //Try to parse the amount to an int. If we fail, we make it -1
-						if err != nil {
-							item.Amount = -1
-						} else {
-							item.Amount = num
-						}
-						break
-					}
-				case 1:
-					{
-						item.Name = itemInfo[j]
-						break
-					}
-				case 2:
-					{
-						item.Description = itemInfo[j]
-						break
-					}
-				}
-			}
-			inventory = append(inventory, item) //Append to the inventory array
-		}
+//Writes a value as a json response with the given status code
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+//Writes a json response holding only a message, in the same format the fail page uses
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+//Finds the user's sheet a request refers to. Sheets are referred to by their ID, or by their name in links made before sheets had IDs
+func (s *server) findSheet(username string, ref string) (pages.Sheet, error) {
+	sheet, err := s.store.GetSheetByID(username, ref)
+	if errors.Is(err, db.ErrNotFound) {
+		return s.store.GetSheet(username, ref)
 	}
-	return inventory
-}
-
-//Takes in a list of spells from the submitted sheet, and then parses them into structs to be a spell array
-func parseSpells(spellList []string) []pages.Spell {
-	//Each item in the list is a string in the format "<name>:<level>:<description>"
-	spells := []pages.Spell{}             //Array we're returning
-	for i := 0; i < len(spellList); i++ { //Loop through each item
-		spell := pages.Spell{}                        //Struct we'll be filling with data
-		spellInfo := strings.Split(spellList[i], ":") //Separate the name, level and description
-		if len(spellInfo) == 3 {                      //Load the data into the struct
-			for j := 0; j < len(spellInfo); j++ {
-				switch j {
-				case 0:
-					{
-						spell.Name = spellInfo[j]
-						break
-					}
-				case 1:
-					{
-						num, err := strconv.Atoi(spellInfo[j]) //Try to parse the level to an int.
-						if err != nil {
-							spell.Level = -1
-						} else {
-							spell.Level = num
-						}
-						break
-					}
-				case 2:
-					{
-						spell.Description = spellInfo[j]
-						break
-					}
-				}
-			}
-			spells = append(spells, spell) //Append to the spells array
-		}
+	return sheet, err
+}
+
+//Gives the name of the user's sheet a request refers to, for the store calls that take a name
+func (s *server) sheetName(username string, ref string) (string, error) {
+	sheet, err := s.findSheet(username, ref)
+	return sheet.Name, err
+}
+
+//Changes the user's sheet a request refers to, by its ID or name
+func (s *server) modifySheet(username string, ref string, change func(*pages.Sheet) error) (pages.Sheet, error) {
+	name, err := s.sheetName(username, ref)
+	if err != nil {
+		return pages.Sheet{}, err
 	}
-	return spells
+	return s.store.ModifySheet(username, name, change)
 }
 
 //Hashes password using bcrypt
@@ -211,6 +288,19 @@ func getUserName(r *http.Request) (userName string) {
 	return userName
 }
 
+//Makes the dice roller of the server. $DICE_SEED makes the rolls repeat between runs, which helps with testing
+func newRoller() (*dice.Roller, error) {
+	seed := os.Getenv("DICE_SEED")
+	if seed == "" {
+		return dice.NewRoller(time.Now().UnixNano()), nil
+	}
+	num, err := strconv.ParseInt(seed, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("$DICE_SEED: %v", err)
+	}
+	return dice.NewRoller(num), nil
+}
+
 //Gets the port environment variable
 func determineListenAddress() (string, error) {
 	port := os.Getenv("PORT")
@@ -221,7 +311,7 @@ func determineListenAddress() (string, error) {
 }
 
 //Main handler. Loads the index page
-func indexHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) indexHandler(w http.ResponseWriter, r *http.Request) {
 	data := pages.Index{}
 	username := getUserName(r)
 	if username == "" {
@@ -230,13 +320,12 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		data.Title = "Welcome " + username
 		data.LoggedIn = true
-		sheets, err := db.GetSheets(username)
+		sheets, err := s.store.GetSheets(username)
+		data.Sheets = sheets
 		if err != nil {
-			data.Sheets = []string{"Could not load sheets from database"}
+			data.Message = "Could not load sheets from database"
 		} else if len(sheets) == 0 {
-			data.Sheets = []string{"You have no saved sheets"}
-		} else {
-			data.Sheets = sheets
+			data.Message = "You have no saved sheets"
 		}
 	}
 	pageData, err := json.Marshal(data)
@@ -248,17 +337,17 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 //Handler that loads the login page
-func loginPageHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) loginPageHandler(w http.ResponseWriter, r *http.Request) {
 	t, _ := template.ParseFiles("./templates/login.html")
 	t.Execute(w, nil)
 }
 
 //Handler that tries to log the user in
-func loginHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 	if username != "" && password != "" {
-		ok, err := db.CheckUser(username, password)
+		ok, err := s.store.CheckUser(username, password)
 		if err != nil || !ok { //Load failure page if the login credentials were incorrect
 			actionFailed(w, `{"message":"Could not match password or username"}`)
 		} else if ok { //If the credentials were ok, we set up the session value to be the username and route back to index
@@ -271,24 +360,26 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 //Handler deletes the session cookie and routes back to index
-func logoutHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) logoutHandler(w http.ResponseWriter, r *http.Request) {
 	clearSession(w)
 	http.Redirect(w, r, "/index/", 303)
 }
 
 //Handler loads the sheet page
-func sheetHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) sheetHandler(w http.ResponseWriter, r *http.Request) {
 	username := getUserName(r)
 	page := pages.SheetPage{}
 	if username == "" { //Routes back to index if accessed without being logged in yet
 		http.Redirect(w, r, "/index/", 303)
 	} else {
 		page.LoggedIn = true
-		sheet, err := db.GetSheet(username, r.FormValue("sheet"))
+		sheet, err := s.findSheet(username, r.FormValue("sheet"))
 		if err != nil { //Loads error page if we failed to load the character sheet
 			actionFailed(w, `{"message":"`+err.Error()+`"}`)
 		} else { //Load the sheet page
+			rules.UpdateSlots(&sheet) //Only for the page. Sheets saved before slots were tracked get theirs stored by -repair
 			page.CharacterSheet = sheet
+			page.Derived = rules.Derive(sheet)
 			pageData, err := json.Marshal(page)
 			if err != nil {
 				panic(err)
@@ -300,21 +391,20 @@ func sheetHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 //Handler tries to register a user
-func registerHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) registerHandler(w http.ResponseWriter, r *http.Request) {
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 	user := db.User{}
 	if username == "" || password == "" { //Routes to index if the form values arent set
 		http.Redirect(w, r, "/index/", 303)
 	} else if username != "" && password != "" {
-		exist, err := db.CheckUserName(r.FormValue("username"))
+		exist, err := s.store.CheckUserName(r.FormValue("username"))
 		if err != nil { //Loads error page if we fail to check the suer
 			actionFailed(w, `{"message":"`+err.Error()+`"}`)
 		} else if !exist {
 			user.Username = r.FormValue("username")
 			user.Password = makeHash([]byte(r.FormValue("password")))
-			user.Sheets = []string{}
-			err := db.RegisterUser(user)
+			err := s.store.RegisterUser(user)
 			if err != nil { //Load fail page if we fail to register the user
 				actionFailed(w, `{"message":"`+err.Error()+`"}`)
 			} else { //Route to the login page if all is well
@@ -327,144 +417,251 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 //Handler loads the register page
-func registerPageHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) registerPageHandler(w http.ResponseWriter, r *http.Request) {
 	t, _ := template.ParseFiles("./templates/register.html")
 	t.Execute(w, nil)
 }
 
 //Handler tries to register a new sheet
-func newSheetHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) newSheetHandler(w http.ResponseWriter, r *http.Request) {
 	username := getUserName(r)
 	if username != "" { //If the user is logged in, we try to load the sheet
-		sheet := pages.Sheet{} //Fill a sheet object with all the relevant values
-		r.ParseForm()
-		age, _ := strconv.Atoi(r.Form["age"][0])
-		level, _ := strconv.Atoi(r.Form["level"][0])
-		curEx, _ := strconv.Atoi(r.Form["currentExpirience"][0])
-		nexEx, _ := strconv.Atoi(r.Form["nextExpirience"][0])
-		prof, _ := strconv.Atoi(r.Form["proficiency"][0])
-		str, _ := strconv.Atoi(r.Form["strength"][0])
-		dex, _ := strconv.Atoi(r.Form["dexterity"][0])
-		con, _ := strconv.Atoi(r.Form["constitution"][0])
-		intel, _ := strconv.Atoi(r.Form["intelligence"][0])
-		wis, _ := strconv.Atoi(r.Form["wisdom"][0])
-		cha, _ := strconv.Atoi(r.Form["charisma"][0])
-		ac, _ := strconv.Atoi(r.Form["ac"][0])
-		spd, _ := strconv.Atoi(r.Form["speed"][0])
-		init, _ := strconv.Atoi(r.Form["initiative"][0])
-		cp, _ := strconv.Atoi(r.Form["cp"][0])
-		sp, _ := strconv.Atoi(r.Form["sp"][0])
-		ep, _ := strconv.Atoi(r.Form["ep"][0])
-		gp, _ := strconv.Atoi(r.Form["gp"][0])
-		pp, _ := strconv.Atoi(r.Form["pp"][0])
-		passivePer, _ := strconv.Atoi(r.Form["passivePerception"][0])
-		health, _ := strconv.Atoi(r.Form["health"][0])
-		scores := pages.Abilities{
-			Strength:     str,
-			Dexterity:    dex,
-			Constitution: con,
-			Intelligence: intel,
-			Wisdom:       wis,
-			Charisma:     cha,
-		}
-		money := pages.Coin{
-			CP: cp,
-			SP: sp,
-			EP: ep,
-			GP: gp,
-			PP: pp,
+		sheet, errs := parseSheetForm(r, username)
+		if len(errs) > 0 { //Show the form again with what was wrong, instead of saving a bad sheet
+			loadSheetForm(w, http.StatusUnprocessableEntity, pages.SheetForm{CharacterSheet: sheet, Errors: errs, Values: r.Form})
+			return
 		}
-		hitDie := pages.HitDice{
-			Name:   r.Form["hitDie"][0],
-			Amount: level,
-		}
-		var inventory = []pages.Item{}
-		if len(r.Form["inventory"]) > 0 {
-			inventory = parseItems(strings.Split(r.Form["inventory"][0], ","))
-		}
-		var feats = []pages.Feat{}
-		if len(r.Form["feats"]) > 0 {
-			featsOrAllies := parseFeatsAndAllies(strings.Split(r.Form["feats"][0], ","))
-			for _, feat := range featsOrAllies {
-				feats = append(feats, pages.Feat{Name: feat.Name, Description: feat.Description})
-			}
-		}
-		var allies = []pages.Ally{}
-		if len(r.Form["allies"]) > 0 {
-			featsOrAllies := parseFeatsAndAllies(strings.Split(r.Form["allies"][0], ","))
-			for _, ally := range featsOrAllies {
-				allies = append(allies, pages.Ally{Name: ally.Name, Description: ally.Description})
-			}
-		}
-		var spells = []pages.Spell{}
-		if len(r.Form["spells"]) > 0 {
-			spells = parseSpells(strings.Split(r.Form["spells"][0], ","))
-		}
-		sheet.Owner = username
-		sheet.Name = r.Form["name"][0]
-		sheet.CharacterName = r.Form["characterName"][0]
-		sheet.Age = age
-		sheet.Weight = r.Form["weight"][0]
-		sheet.Height = r.Form["height"][0]
-		sheet.Size = r.Form["size"][0]
-		sheet.Gender = r.Form["gender"][0]
-		sheet.EyeColor = r.Form["eyeColor"][0]
-		sheet.Skin = r.Form["skin"][0]
-		sheet.Class = r.Form["class"][0]
-		sheet.Race = r.Form["race"][0]
-		sheet.Level = level
-		sheet.Allignment = r.Form["allignment"][0]
-		sheet.Race = r.Form["race"][0]
-		sheet.CurrentExpirience = curEx
-		sheet.NextExpirience = nexEx
-		sheet.Proficiency = prof
-		sheet.Scores = scores
-		sheet.Saves = r.Form["saves"]
-		sheet.ProficientSkills = r.Form["proficientSkills"]
-		sheet.ExpertSkills = r.Form["expertSkills"]
-		sheet.Languages = r.Form["languages"]
-		sheet.Tools = r.Form["tools"]
-		sheet.Vehicles = r.Form["vehicles"]
-		sheet.Weapons = r.Form["weapons"]
-		sheet.Armor = r.Form["armor"]
-		sheet.Inventory = inventory
-		sheet.AC = ac
-		sheet.Initiative = init
-		sheet.Speed = spd
-		sheet.Ideals = r.Form["ideals"][0]
-		sheet.Bonds = r.Form["bonds"][0]
-		sheet.Flaw = r.Form["flaw"][0]
-		sheet.Feats = feats
-		sheet.Money = money
-		sheet.PassivePerception = passivePer
-		sheet.Backstory = r.Form["backstory"][0]
-		sheet.Allies = allies
-		sheet.HitDie = hitDie
-		sheet.Health = health
-		sheet.Spells = spells
-		err := db.RegisterSheet(username, sheet) //Attemt to register the sheet
-		if err != nil {                          //Load fail page if we fail to register the sheet
+		err := s.store.RegisterSheet(username, sheet) //Attemt to register the sheet
+		if err != nil {                               //Load fail page if we fail to register the sheet
 			actionFailed(w, `{"message":"`+err.Error()+`"}`)
 		} else { //Route back to index if all is well
 			http.Redirect(w, r, "/index/", 303)
 		}
+	} else { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
 	}
-	http.Redirect(w, r, "/index/", 303)
 }
 
-//Handler laods the new sheet page
-func newSheetPageHandler(w http.ResponseWriter, r *http.Request) {
+//Loads the sheet form with the given data
+func loadSheetForm(w http.ResponseWriter, status int, form pages.SheetForm) {
+	pageData, err := json.Marshal(form)
+	if err != nil {
+		panic(err)
+	}
 	t, _ := template.ParseFiles("./templates/newSheet.html")
-	t.Execute(w, nil)
+	w.WriteHeader(status)
+	t.Execute(w, string(pageData))
+}
+
+//Handler laods the new sheet page
+func (s *server) newSheetPageHandler(w http.ResponseWriter, r *http.Request) {
+	loadSheetForm(w, http.StatusOK, pages.SheetForm{})
+}
+
+//Handler loads the sheet form filled in with an existing sheet, so it can be edited
+func (s *server) editSheetPageHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Routes back to index if accessed without being logged in yet
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	sheet, err := s.findSheet(username, r.FormValue("sheet"))
+	if err != nil { //Loads error page if we failed to load the character sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	loadSheetForm(w, http.StatusOK, pages.SheetForm{CharacterSheet: sheet, Edit: true})
+}
+
+//Handler tries to replace a stored sheet with the edited one
+func (s *server) updateSheetHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username != "" {
+		sheet, errs := parseSheetForm(r, username)
+		if len(errs) > 0 { //Show the form again with what was wrong, instead of saving a bad sheet
+			loadSheetForm(w, http.StatusUnprocessableEntity, pages.SheetForm{CharacterSheet: sheet, Edit: true, Errors: errs, Values: r.Form})
+			return
+		}
+		updated, err := s.store.ModifySheet(username, sheet.Name, func(stored *pages.Sheet) error { //Attempt to update the sheet
+			sheet.SpellSlots = stored.SpellSlots //The form doesn't show the used slots, so keep them
+			sheet.PactSlots = stored.PactSlots
+			rules.UpdateSlots(&sheet)
+			*stored = keepTracked(*stored, sheet)
+			return nil
+		})
+		if err != nil { //Load fail page if we fail to update the sheet
+			actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		} else { //Route to the updated sheet if all is well
+			http.Redirect(w, r, "/sheet/?sheet="+url.QueryEscape(updated.ID), 303)
+		}
+	} else { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
+	}
+}
+
+//Handler applies a list of changes to one of the user's sheets, and responds with the updated sheet.
+//Expects a json body like {"sheet":"<name>","ops":[{"op":"inc","path":"inventory.3.amount","value":-1}]}
+func (s *server) patchSheetHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	if r.Method != http.MethodPatch && r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use PATCH to update a sheet")
+		return
+	}
+	var body struct { //Help struct that holds the decoded request
+		Sheet string       `json:"sheet"`
+		Ops   []db.SheetOp `json:"ops"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Could not read the update: "+err.Error())
+		return
+	}
+	sheet := pages.Sheet{}
+	name, err := s.sheetName(username, body.Sheet)
+	if err == nil {
+		sheet, err = s.store.PatchSheet(username, name, body.Ops)
+	}
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+	} else {
+		writeJSON(w, http.StatusOK, sheet)
+	}
+}
+
+//Handler changes the hit points of one of the user's sheets by the rules, and responds with the updated sheet.
+//Expects a json body like {"sheet":"<name>","action":"damage","amount":7}
+func (s *server) hitPointsHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to change hit points")
+		return
+	}
+	var body struct { //Help struct that holds the decoded request
+		Sheet string `json:"sheet"`
+		rules.HPAction
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Could not read the change: "+err.Error())
+		return
+	}
+	sheet, err := s.modifySheet(username, body.Sheet, func(sheet *pages.Sheet) error {
+		return rules.ApplyHP(sheet, body.HPAction, s.dice.Die)
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+	} else {
+		writeJSON(w, http.StatusOK, sheet)
+	}
+}
+
+//Handler takes a short or long rest with one of the user's sheets, and responds with the updated sheet.
+//Expects a json body like {"sheet":"<name>","type":"short","dice":[{"class":"Fighter","count":2}]}
+func (s *server) restHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to take a rest")
+		return
+	}
+	var body struct { //Help struct that holds the decoded request
+		Sheet string `json:"sheet"`
+		rules.Rest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Could not read the rest: "+err.Error())
+		return
+	}
+	sheet, err := s.modifySheet(username, body.Sheet, func(sheet *pages.Sheet) error {
+		_, err := rules.TakeRest(sheet, body.Rest, s.dice.Die, time.Now())
+		return err
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+	} else {
+		writeJSON(w, http.StatusOK, sheet)
+	}
+}
+
+//Handler adds or removes a condition, or sets the exhaustion level, of one of the user's sheets, and responds with the updated sheet.
+//Expects a json body like {"sheet":"<name>","action":"add","condition":{"name":"Poisoned","duration":"1 hour"}}
+func (s *server) conditionsHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to change conditions")
+		return
+	}
+	var body struct { //Help struct that holds the decoded request
+		Sheet string `json:"sheet"`
+		rules.ConditionAction
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Could not read the change: "+err.Error())
+		return
+	}
+	sheet, err := s.modifySheet(username, body.Sheet, func(sheet *pages.Sheet) error {
+		return rules.ApplyCondition(sheet, body.ConditionAction)
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+	} else {
+		writeJSON(w, http.StatusOK, sheet)
+	}
+}
+
+//Handler spends, receives or consolidates the coins of one of the user's sheets, and responds with the updated sheet.
+//Expects a json body like {"sheet":"<name>","action":"spend","amount":"12 gp 5 sp","description":"Rope and rations"}
+func (s *server) coinsHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to change coins")
+		return
+	}
+	var body struct { //Help struct that holds the decoded request
+		Sheet string `json:"sheet"`
+		rules.CoinAction
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Could not read the change: "+err.Error())
+		return
+	}
+	sheet, err := s.modifySheet(username, body.Sheet, func(sheet *pages.Sheet) error {
+		_, err := rules.ApplyCoins(sheet, body.CoinAction, time.Now())
+		return err
+	})
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+	} else {
+		writeJSON(w, http.StatusOK, sheet)
+	}
 }
 
 //Handler tries to delete a given sheet from the database
-func deleteHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) deleteHandler(w http.ResponseWriter, r *http.Request) {
 	username := getUserName(r)
 	if username != "" {
-		sheet := r.FormValue("yes")            //Get the name of the sheet
-		err := db.DeleteSheet(username, sheet) //Attempt to delete the sheet
-		if err != nil {                        //Load error page on failure
+		name, err := s.sheetName(username, r.FormValue("yes")) //Get the name of the sheet
+		if err == nil {
+			err = s.store.DeleteSheet(username, name) //Attempt to delete the sheet
+		}
+		if err != nil { //Load error page on failure
 			actionFailed(w, `{"message":"`+err.Error()+`"}`)
 		} else { //Route back to index if all is well
 			http.Redirect(w, r, "/index/", 303)
@@ -475,11 +672,16 @@ func deleteHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 //Handler loads the delete page
-func deletePageHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) deletePageHandler(w http.ResponseWriter, r *http.Request) {
 	username := getUserName(r)
 	page := pages.DeletePage{}
 	if username != "" {
-		page.SheetName = r.FormValue("delete")
+		sheet, err := s.findSheet(username, r.FormValue("delete"))
+		if err != nil {
+			actionFailed(w, `{"message":"`+err.Error()+`"}`)
+			return
+		}
+		page.SheetID, page.SheetName = sheet.ID, sheet.Name
 		t, _ := template.ParseFiles("./templates/delete.html")
 		t.Execute(w, page)
 	} else {
@@ -0,0 +1,22 @@
+package db
+
+import pages "Pages"
+
+//CloneSheet copies one of the user's sheets under a new name, and gives the copy. Reset is called on the copy before it
+//is saved, to clear what the new character shouldn't start with. The copy gets an ID and a revision history of its own,
+//and starts without the roll log of the original
+func CloneSheet(store Store, user string, name string, newName string, reset func(sheet *pages.Sheet)) (pages.Sheet, error) {
+	sheet, err := store.GetSheet(user, name)
+	if err != nil {
+		return pages.Sheet{}, err
+	}
+	sheet.Name = newName
+	sheet.Rolls = nil
+	if reset != nil {
+		reset(&sheet)
+	}
+	if err = store.RegisterSheet(user, sheet); err != nil {
+		return pages.Sheet{}, err
+	}
+	return store.GetSheet(user, newName)
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	pages "Pages"
+	"errors"
+	"testing"
+)
+
+func TestCloneSheet(t *testing.T) {
+	store := testStore(t)
+	original := registerSheet(t, store, pages.Sheet{Name: "Test", CharacterName: "Bo", CurrentExpirience: 10, Languages: []string{"Common"}})
+	store.ModifySheet("bob", "Test", func(stored *pages.Sheet) error {
+		stored.Rolls = []pages.RollRecord{{Notation: "1d20"}}
+		return nil
+	})
+	clone, err := CloneSheet(store, "bob", "Test", "Copy", func(sheet *pages.Sheet) {
+		sheet.CurrentExpirience = 0
+		sheet.Languages[0] = "Elvish"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.Name != "Copy" || clone.CharacterName != "Bo" || clone.CurrentExpirience != 0 || clone.ID == original.ID || clone.Version != 0 || clone.Rolls != nil {
+		t.Errorf("CloneSheet gave %+v", clone)
+	}
+	stored, _ := store.GetSheet("bob", "Test")
+	if stored.CurrentExpirience != 10 || stored.Languages[0] != "Common" || len(stored.Rolls) != 1 {
+		t.Errorf("cloning changed the original to %+v", stored)
+	}
+	if revisions, _ := store.ListRevisions("bob", "Copy"); len(revisions) != 1 || revisions[0].Description != createdDescription {
+		t.Errorf("the clone has the history %+v, want its own", revisions)
+	}
+	if _, err := CloneSheet(store, "bob", "Test", "Plain", nil); err != nil {
+		t.Errorf("cloning without a reset gave %v", err)
+	}
+}
+
+func TestCloneSheetRejected(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, pages.Sheet{Name: "Test"})
+	registerSheet(t, store, pages.Sheet{Name: "Trashed"})
+	store.DeleteSheet("bob", "Trashed")
+	tests := []struct {
+		name    string
+		from    string
+		newName string
+		want    error
+	}{
+		{"taken name", "Test", "Test", ErrExists},
+		{"name in the trash", "Test", "Trashed", ErrInTrash},
+		{"missing sheet", "Missing", "Copy", ErrNotFound},
+		{"trashed sheet", "Trashed", "Copy", ErrNotFound},
+	}
+	for _, test := range tests {
+		if _, err := CloneSheet(store, "bob", test.from, test.newName, nil); !errors.Is(err, test.want) {
+			t.Errorf("%s: CloneSheet gave %v, want %v", test.name, err, test.want)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package db
 import (
 	pages "Pages"
 	"context"
-	"os"
+	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,221 +14,547 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-//Conncetion link to the mongodb database.
-var connection = os.Getenv("CONNECTION")
+//Name of the mongodb database holding our collections
+const database = "CharacterSheets"
 
-//User represents a user in the database
+//How many times ModifySheet tries again when another write got to the sheet first
+const modifyRetries = 5
+
+//User represents a user in the database. The user's sheets are found through their owner field
 type User struct {
-	Username string   `json:"username"`
-	Password string   `json:"password"`
-	Sheets   []string `json:"sheets"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+//MongoConfig holds the settings used to set up the mongodb client
+type MongoConfig struct {
+	Connection     string        //Conncetion link to the mongodb database
+	MaxPoolSize    uint64        //Maximum amount of pooled connections. 0 uses the driver's default
+	ConnectTimeout time.Duration //How long we wait for the initial connection
+	QueryTimeout   time.Duration //How long a single store call may take
+	Repairing      bool          //Connects for Repair, skipping the checks and indexes that need a repaired database
+}
+
+//MongoStore is a Store backed by a mongodb database. It shares one pooled client between all calls
+type MongoStore struct {
+	client  *mongo.Client //Long-lived client, safe for concurrent use
+	timeout time.Duration //How long a single store call may take
+}
+
+//NewMongoStore connects to the database described by the config, and makes a MongoStore using that connection
+func NewMongoStore(config MongoConfig) (*MongoStore, error) {
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = 10 * time.Second
+	}
+	if config.QueryTimeout <= 0 {
+		config.QueryTimeout = 5 * time.Second
+	}
+	opts := options.Client().ApplyURI(config.Connection).
+		SetConnectTimeout(config.ConnectTimeout).
+		SetServerSelectionTimeout(config.ConnectTimeout)
+	if config.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(config.MaxPoolSize)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Ping(ctx, nil); err != nil { //Make sure the database is actually reachable before we start serving
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	store := &MongoStore{client: client, timeout: config.QueryTimeout}
+	if !config.Repairing {
+		if err = store.checkRepaired(ctx); err != nil {
+			client.Disconnect(context.Background())
+			return nil, err
+		}
+		if err = store.ensureIndexes(ctx); err != nil {
+			client.Disconnect(context.Background())
+			return nil, fmt.Errorf("could not build the indexes, run with -repair to fix the documents in the way: %w", err)
+		}
+	}
+	return store, nil
+}
+
+//Refuses a database that still has the sheet lists of older versions on its users. Sheets created next to those lists
+//aren't on them, so a later Repair would move them to the trash. Revisions kept by sheet name are refused as well, as
+//a rename would leave them to whichever sheet takes the name next
+func (s *MongoStore) checkRepaired(ctx context.Context) error {
+	legacy, err := s.collection("users").CountDocuments(ctx, bson.M{"sheets": bson.M{"$exists": true}}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if legacy > 0 {
+		return errors.New("users still have the sheet lists of an older version, stop the old server and run with -repair first")
+	}
+	byName, err := s.collection("revisions").CountDocuments(ctx, bson.M{"sheetid": nil}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if byName > 0 {
+		return errors.New("revisions are still kept by sheet name like in an older version, stop the old server and run with -repair first")
+	}
+	return nil
+}
+
+//Builds the indexes that keep usernames, sheet names and sheet IDs unique, and keep the lookups fast. Indexes that already exist are left as they are
+func (s *MongoStore) ensureIndexes(ctx context.Context) error {
+	withID := bson.M{"id": bson.M{"$gt": ""}} //Sheets stored before IDs were added have none until their next write
+	indexes := map[string][]mongo.IndexModel{
+		"users": {
+			{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"sheets": {
+			{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(withID)},
+		},
+		"revisions": {
+			{Keys: bson.D{{Key: "sheetid", Value: 1}, {Key: "version", Value: -1}}},
+		},
+	}
+	for collection, models := range indexes {
+		if _, err := s.collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("%s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+//Checks if a write failed because it would break one of the unique indexes
+func isDuplicate(err error) bool {
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == 11000 {
+				return true
+			}
+		}
+	}
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && commandErr.Code == 11000
+}
+
+//Close disconnects the client from the database
+func (s *MongoStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	return s.client.Disconnect(ctx)
+}
+
+//Makes the context a single store call runs under
+func (s *MongoStore) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+//Gets a collection from our database
+func (s *MongoStore) collection(name string) *mongo.Collection {
+	return s.client.Database(database).Collection(name)
 }
 
 //CheckUser checks if a user exists in the database
-func CheckUser(user string, pass string) (bool, error) {
-	ok := false         //Boolean that determines if the match was valid
+func (s *MongoStore) CheckUser(user string, pass string) (bool, error) {
 	var result struct { //Help struct that saves the data retrieved from the database
 		Username string `json:"username"`
 		Password string `json:"password"`
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //Connect to the database
-	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
 	filter := bson.M{"username": user} //Query filter we use to fetch from the database
-	defer func() {                     //We disconnect the client after the method is done
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-	if err != nil { //End method if we failed to connect
-		return ok, err
-	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //We get the mongodb collection
+	ctx, cancel := s.context()
 	defer cancel()
-	collection := client.Database("CharacterSheets").Collection("users")
-	err = collection.FindOne(context.TODO(), filter).Decode(&result)
-	if err != nil { //End method if we failed to get collection
-		return ok, err
+	err := s.collection("users").FindOne(ctx, filter).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return false, ErrNotFound
+	} else if err != nil {
+		return false, err
 	}
-	pwd := []byte(pass)
-	dpwd := []byte(result.Password)
-	passErr := bcrypt.CompareHashAndPassword(dpwd, pwd) //Check if the hashed password in the database matches the given password
+	passErr := bcrypt.CompareHashAndPassword([]byte(result.Password), []byte(pass)) //Check if the hashed password in the database matches the given password
 	if passErr != nil {
-		return ok, passErr //End the method if the hashes didnt match
+		return false, passErr
 	}
-	if user == result.Username { //If the username was correct we set our boolean to sucess
-		ok = true
-	}
-	return ok, nil
+	return user == result.Username, nil
 }
 
 //CheckUserName checks the database if the name can be found
-func CheckUserName(user string) (bool, error) {
-	ok := false         //Boolean that determines if the match was valid
+func (s *MongoStore) CheckUserName(user string) (bool, error) {
 	var result struct { //Help struct that saves the data retrieved from the database
 		Username string `json:"username"`
 	}
-	filter := bson.M{"username": user}                                       //Query filter we use to fetch from the database
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //Connect to the database
-	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-	if err != nil { //End if we failed to connect
-		return false, err
-	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Getting collection and try to find the user
+	filter := bson.M{"username": user} //Query filter we use to fetch from the database
+	ctx, cancel := s.context()
 	defer cancel()
-	collection := client.Database("CharacterSheets").Collection("users")
-	err = collection.FindOne(context.TODO(), filter).Decode(&result)
-	if err != nil { //End if we failed. Error is set to nil, because we expect a failure
+	err := s.collection("users").FindOne(ctx, filter).Decode(&result)
+	if err == mongo.ErrNoDocuments { //Not finding the user is the expected outcome, so it's not an error
 		return false, nil
+	} else if err != nil {
+		return false, err
 	}
-	if user == result.Username { //If we found a match, the ok is set to true
-		ok = true
-	}
-	return ok, nil
+	return user == result.Username, nil
 }
 
-//GetSheets gets the list of character sheet names from a given user in the database
-func GetSheets(user string) ([]string, error) {
-	var result struct { //Help struct that saves the data retrieved from the database
-		Sheets []string `json:"sheets"`
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //Connect to database
+//GetSheets lists a given user's character sheets that aren't in the trash, in alphabetical order
+func (s *MongoStore) GetSheets(user string) ([]pages.SheetInfo, error) {
+	sheets := []pages.SheetInfo{}                   //Objects that hold the retrieved sheets
+	filter := bson.M{"owner": user, "deleted": nil} //Query filter we use to fetch from the database
+	opts := options.Find().SetSort(bson.M{"name": 1}).SetProjection(bson.M{"id": 1, "name": 1, "charactername": 1})
+	ctx, cancel := s.context()
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
-	filter := bson.M{"username": user} //Query filter we use to fetch from the database
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
+	cursor, err := s.collection("sheets").Find(ctx, filter, opts)
+	if err == nil {
+		err = cursor.All(ctx, &sheets)
+	}
+	if err != nil {
+		return []pages.SheetInfo{}, err
+	}
+	if len(sheets) == 0 { //Tell a user without sheets apart from a missing one
+		if exists, err := s.CheckUserName(user); err != nil {
+			return sheets, err
+		} else if !exists {
+			return sheets, ErrNotFound
 		}
-	}()
-	if err != nil { //End if we failed to connect. Return empty array
-		result.Sheets = []string{}
-		return result.Sheets, err
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Getting collection and query for the sheets
+	return sheets, nil
+}
+
+//GetSheet retrieves a given user's character sheet from the database
+func (s *MongoStore) GetSheet(username string, sheetname string) (pages.Sheet, error) {
+	sheet := pages.Sheet{}                                                 //Object that holds the retrieved sheet
+	filter := bson.M{"owner": username, "name": sheetname, "deleted": nil} //Query filter we use to fetch from the database. Sheets in the trash are left out
+	ctx, cancel := s.context()
 	defer cancel()
-	collection := client.Database("CharacterSheets").Collection("users")
-	err = collection.FindOne(context.TODO(), filter).Decode(&result)
-	if err != nil { //End if we failed to connect. Return empty array
-		result.Sheets = []string{}
-		return result.Sheets, err
+	err := s.collection("sheets").FindOne(ctx, filter).Decode(&sheet)
+	if err == mongo.ErrNoDocuments { //Report a missing sheet the same way every store does
+		return sheet, ErrNotFound
 	}
-	return result.Sheets, nil
+	sheet.Migrate() //Sheets saved by older versions get the current layout here, and keep it on their next write
+	return sheet, err
 }
 
-//GetSheet retrieves a given user's character sheet from the database
-func GetSheet(username string, sheetname string) (pages.Sheet, error) {
-	sheet := pages.Sheet{}                                                   //Object that holds
-	filter := bson.M{"owner": username, "name": sheetname}                   //Query filter we use to fetch from the database
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //Connect to the database
-	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-	if err != nil { //End if we failed to connect.
-		return sheet, err
+//GetSheetByID retrieves a given user's character sheet by its ID
+func (s *MongoStore) GetSheetByID(username string, id string) (pages.Sheet, error) {
+	sheet := pages.Sheet{}                                        //Object that holds the retrieved sheet
+	filter := bson.M{"owner": username, "id": id, "deleted": nil} //Query filter we use to fetch from the database
+	if id == "" {
+		return sheet, ErrNotFound
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Get the collection and retrieve the sheet data
+	ctx, cancel := s.context()
 	defer cancel()
-	collection := client.Database("CharacterSheets").Collection("sheets")
-	err = collection.FindOne(context.TODO(), filter).Decode(&sheet)
+	err := s.collection("sheets").FindOne(ctx, filter).Decode(&sheet)
+	if err == mongo.ErrNoDocuments {
+		return sheet, ErrNotFound
+	}
+	sheet.Migrate()
 	return sheet, err
 }
 
 //RegisterUser registers a new user in the database
-func RegisterUser(user User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //Connect to the database
+func (s *MongoStore) RegisterUser(user User) error {
+	ctx, cancel := s.context()
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-	if err != nil { //End if we fail
+	_, err := s.collection("users").InsertOne(ctx, user)
+	if isDuplicate(err) {
+		return ErrExists
+	}
+	return err
+}
+
+//RegisterSheet registers a new character sheet with a user. The sheet is a single document, so it is stored in one write
+func (s *MongoStore) RegisterSheet(user string, sheet pages.Sheet) error {
+	filterTrash := bson.M{"owner": user, "name": sheet.Name, "deleted": bson.M{"$ne": nil}} //Query filter to find a trashed sheet with the same name
+	sheet.ID = newID()
+	sheet.Owner = user
+	sheet.Version = 0 //New sheets start their versions over, whatever they were sent with
+	sheet.Deleted = nil
+	ctx, cancel := s.context()
+	defer cancel()
+	trashed, err := s.collection("sheets").CountDocuments(ctx, filterTrash)
+	if err != nil {
 		return err
+	} else if trashed > 0 { //Its name is still taken, so it can be restored
+		return ErrInTrash
+	}
+	_, err = s.collection("sheets").InsertOne(ctx, sheet) //Insert the new sheet
+	if isDuplicate(err) {
+		return ErrExists
+	} else if err != nil {
+		return err
+	}
+	s.saveRevision(user, sheet, createdDescription)
+	return nil
+}
+
+//UpdateSheet replaces the user's stored sheet that has the same name as the given sheet
+func (s *MongoStore) UpdateSheet(user string, sheet pages.Sheet) error {
+	_, err := s.ModifySheet(user, sheet.Name, func(stored *pages.Sheet) error {
+		*stored = sheet
+		return nil
+	})
+	return err
+}
+
+//ModifySheet reads a stored sheet, lets change modify it, and writes it back. If someone else wrote to the sheet
+//in the meantime, the change is run again on the fresh sheet, so no write is lost
+func (s *MongoStore) ModifySheet(user string, name string, change func(*pages.Sheet) error) (pages.Sheet, error) {
+	return s.modifySheet(user, name, change, "")
+}
+
+//Does the work of ModifySheet, saving the revision under the given description. An empty one is made from the changes
+func (s *MongoStore) modifySheet(user string, name string, change func(*pages.Sheet) error, description string) (pages.Sheet, error) {
+	for i := 0; i < modifyRetries; i++ {
+		sheet, err := s.GetSheet(user, name)
+		if err != nil {
+			return sheet, err
+		}
+		version, id := sheet.Version, sheet.ID
+		if err = change(&sheet); err != nil {
+			return sheet, err
+		}
+		if id == "" { //Sheets stored before IDs were added get one on their next write
+			id = newID()
+		}
+		sheet.ID = id
+		sheet.Owner = user
+		sheet.Name = name
+		sheet.Version = version + 1
+		filter := bson.M{"owner": user, "name": name, "version": version, "deleted": nil} //Only replace the sheet if nobody wrote to it or trashed it since we read it
+		if version == 0 {                                                                 //Sheets stored before versions were added have no version field
+			filter["version"] = bson.M{"$in": bson.A{0, nil}}
+		}
+		ctx, cancel := s.context()
+		result, err := s.collection("sheets").ReplaceOne(ctx, filter, sheet)
+		cancel()
+		if err != nil {
+			return sheet, err
+		}
+		if result.MatchedCount == 1 {
+			s.saveRevision(user, sheet, description)
+			return sheet, nil
+		}
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Get collection and try to insert the new user
+	return pages.Sheet{}, ErrConflict
+}
+
+//RenameSheet gives a sheet a new name, keeping its ID and revisions
+func (s *MongoStore) RenameSheet(user string, name string, newName string) (pages.Sheet, error) {
+	sheet := pages.Sheet{}                                                               //Object that holds the renamed sheet
+	filter := bson.M{"owner": user, "name": name, "deleted": nil}                        //Query filter to select the sheet we are renaming
+	filterTrash := bson.M{"owner": user, "name": newName, "deleted": bson.M{"$ne": nil}} //Query filter to find a trashed sheet with the new name
+	update := bson.M{"$set": bson.M{"name": newName}, "$inc": bson.M{"version": 1}}
+	ctx, cancel := s.context()
 	defer cancel()
-	collection := client.Database("CharacterSheets").Collection("users")
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	trashed, err := s.collection("sheets").CountDocuments(ctx, filterTrash)
+	if err != nil {
+		return sheet, err
+	} else if trashed > 0 {
+		return sheet, ErrInTrash
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = s.collection("sheets").FindOneAndUpdate(ctx, filter, update, opts).Decode(&sheet)
+	if err == mongo.ErrNoDocuments {
+		return sheet, ErrNotFound
+	} else if isDuplicate(err) { //The unique index turns down a name that is taken
+		return sheet, ErrExists
+	} else if err != nil {
+		return sheet, err
+	}
+	sheet.Migrate()
+	s.saveRevision(user, sheet, renamedDescription(name))
+	return sheet, nil
+}
+
+//PatchSheet applies a list of changes to a stored sheet, and gives back the updated sheet. The ops are applied here rather
+//than by the database, so they are checked against the rules and behave the same as in the memory store
+func (s *MongoStore) PatchSheet(user string, name string, ops []SheetOp) (pages.Sheet, error) {
+	checked, err := checkOps(ops)
+	if err != nil {
+		return pages.Sheet{}, err
+	}
+	return s.ModifySheet(user, name, func(sheet *pages.Sheet) error {
+		return patchSheet(sheet, checked)
+	})
+}
+
+//DeleteSheet moves a sheet to the trash
+func (s *MongoStore) DeleteSheet(user string, sheet string) error {
+	filter := bson.M{"owner": user, "name": sheet, "deleted": nil} //Query filter to select the correct sheet
+	update := bson.M{"$set": bson.M{"deleted": time.Now()}}        //Update query to mark the sheet as trashed
+	ctx, cancel := s.context()
 	defer cancel()
-	_, err = collection.InsertOne(ctx, user)
+	result, err := s.collection("sheets").UpdateOne(ctx, filter, update)
+	if err == nil && result.MatchedCount == 0 {
+		return ErrNotFound
+	}
 	return err
 }
 
-//RegisterSheet registers a new character sheet with a user
-func RegisterSheet(user string, sheet pages.Sheet) error {
-	filterUser := bson.M{"username": user}                                   //Query filter we use to select the user we want to update
-	update := bson.M{"$push": bson.M{"sheets": sheet.Name}}                  //Update query for the given user's "sheets" array
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //Connect to the database
+//ListTrash lists the sheets in a user's trash, latest deleted first
+func (s *MongoStore) ListTrash(user string) ([]pages.TrashedSheet, error) {
+	trash := []pages.TrashedSheet{}                                //Objects that hold the retrieved sheets
+	filter := bson.M{"owner": user, "deleted": bson.M{"$ne": nil}} //Query filter we use to fetch from the database
+	opts := options.Find().SetSort(bson.M{"deleted": -1}).SetProjection(bson.M{"id": 1, "name": 1, "charactername": 1, "deleted": 1})
+	ctx, cancel := s.context()
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-	if err != nil { //End if we fail
-		return err
+	cursor, err := s.collection("sheets").Find(ctx, filter, opts)
+	if err != nil {
+		return trash, err
+	}
+	err = cursor.All(ctx, &trash)
+	return trash, err
+}
+
+//RestoreSheet takes a sheet out of the trash
+func (s *MongoStore) RestoreSheet(user string, sheet string) error {
+	filter := bson.M{"owner": user, "name": sheet, "deleted": bson.M{"$ne": nil}} //Query filter to select the trashed sheet
+	update := bson.M{"$set": bson.M{"deleted": nil}}                              //Update query to take the sheet out of the trash
+	ctx, cancel := s.context()
+	defer cancel()
+	result, err := s.collection("sheets").UpdateOne(ctx, filter, update)
+	if err == nil && result.MatchedCount == 0 {
+		return ErrNotFound
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Get the user collection and try to update the user's "sheets" array
+	return err
+}
+
+//PurgeSheet deletes a sheet in the trash for good, along with its revisions
+func (s *MongoStore) PurgeSheet(user string, sheet string) error {
+	purged := pages.Sheet{}                                                       //Object that holds the ID of the purged sheet
+	filter := bson.M{"owner": user, "name": sheet, "deleted": bson.M{"$ne": nil}} //Query filter to select the trashed sheet
+	ctx, cancel := s.context()
 	defer cancel()
-	collection := client.Database("CharacterSheets").Collection("users")
-	_, err = collection.UpdateOne(context.TODO(), filterUser, update)
-	if err != nil { //End if we fail
+	opts := options.FindOneAndDelete().SetProjection(bson.M{"id": 1})
+	err := s.collection("sheets").FindOneAndDelete(ctx, filter, opts).Decode(&purged)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	} else if err != nil || purged.ID == "" { //A sheet without an ID never had revisions of its own
 		return err
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Get the sheets collection and try to insert the new sheet
+	_, err = s.collection("revisions").DeleteMany(ctx, bson.M{"sheetid": purged.ID}) //And its history
+	return err
+}
+
+//PurgeTrash purges every user's sheets that went to the trash before the given time, and gives how many were purged
+func (s *MongoStore) PurgeTrash(before time.Time) (int, error) {
+	var expired []struct { //Help struct that saves the sheets retrieved from the database
+		Owner string `json:"owner"`
+		Name  string `json:"name"`
+	}
+	filter := bson.M{"deleted": bson.M{"$ne": nil, "$lt": before}} //Query filter to select the sheets that were in the trash long enough
+	opts := options.Find().SetProjection(bson.M{"owner": 1, "name": 1})
+	ctx, cancel := s.context()
+	cursor, err := s.collection("sheets").Find(ctx, filter, opts)
+	if err == nil {
+		err = cursor.All(ctx, &expired)
+	}
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, sheet := range expired { //Each purge gets its own context, as there may be many of them
+		if err = s.PurgeSheet(sheet.Owner, sheet.Name); err != nil && err != ErrNotFound {
+			return purged, err
+		} else if err == nil {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+//ListRevisions lists the saved revisions of a sheet, newest first and without their sheets
+func (s *MongoStore) ListRevisions(user string, sheet string) ([]pages.Revision, error) {
+	revisions := []pages.Revision{} //Objects that hold the retrieved revisions
+	found, err := s.GetSheet(user, sheet)
+	if err != nil {
+		return revisions, err
+	}
+	filter := bson.M{"sheetid": found.ID}                                                   //Query filter we use to fetch from the database
+	opts := options.Find().SetSort(bson.M{"version": -1}).SetProjection(bson.M{"sheet": 0}) //Newest first, and leave the stored sheets out
+	ctx, cancel := s.context()
 	defer cancel()
-	collection = client.Database("CharacterSheets").Collection("sheets")
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	cursor, err := s.collection("revisions").Find(ctx, filter, opts)
+	if err != nil {
+		return revisions, err
+	}
+	err = cursor.All(ctx, &revisions)
+	return revisions, err
+}
+
+//GetRevision gets one revision of a sheet along with the sheet as it was
+func (s *MongoStore) GetRevision(user string, sheet string, version int) (pages.Revision, error) {
+	revision := pages.Revision{} //Object that holds the retrieved revision
+	found, err := s.GetSheet(user, sheet)
+	if err != nil {
+		return revision, err
+	}
+	filter := bson.M{"sheetid": found.ID, "version": version} //Query filter we use to fetch from the database
+	ctx, cancel := s.context()
 	defer cancel()
-	_, err = collection.InsertOne(ctx, sheet)
-	if err != nil { //End if we fail
-		return err
+	err = s.collection("revisions").FindOne(ctx, filter).Decode(&revision)
+	if err == mongo.ErrNoDocuments {
+		return revision, ErrNotFound
+	} else if err != nil {
+		return revision, err
 	}
-	return err
+	if revision.Sheet == nil {
+		return revision, ErrNotFound
+	}
+	revision.Sheet.Migrate()
+	return revision, nil
+}
+
+//RestoreRevision puts an older revision of a sheet back in place, as a new revision
+func (s *MongoStore) RestoreRevision(user string, sheet string, version int) (pages.Sheet, error) {
+	revision, err := s.GetRevision(user, sheet, version)
+	if err != nil {
+		return pages.Sheet{}, err
+	}
+	return s.modifySheet(user, sheet, func(stored *pages.Sheet) error {
+		restoreRevision(stored, revision)
+		return nil
+	}, restoredDescription(version))
 }
 
-//DeleteSheet deletes a sheet from the database
-func DeleteSheet(user string, sheet string) error {
-	filterUser := bson.M{"username": user}                                   //Query filter to select the right user
-	filterSheets := bson.M{"owner": user, "name": sheet}                     //Query filter to select the correct sheet
-	updateUser := bson.M{"$pull": bson.M{"sheets": sheet}}                   //Update query to remove the sheet from the given user's "sheets" array
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //Connecting to the database
+//Saves a revision of a sheet that was just written, and drops the oldest ones past MaxRevisions. The write already went
+//through, so a revision that can't be saved is logged instead of failing it, as a retried write could be applied twice
+func (s *MongoStore) saveRevision(user string, sheet pages.Sheet, description string) {
+	if err := s.insertRevision(user, sheet, description); err != nil {
+		log.Printf("Saving revision %d of %s/%s: %v\n", sheet.Version, user, sheet.Name, err)
+	}
+}
+
+//Does the work of saveRevision. The changes are described against the revision before this version, rather than the
+//newest one, so concurrent writes that save their revisions out of order still describe their own changes
+func (s *MongoStore) insertRevision(user string, sheet pages.Sheet, description string) error {
+	if sheet.ID == "" {
+		return errors.New("the sheet has no ID to keep its revisions by, run with -repair")
+	}
+	filter := bson.M{"sheetid": sheet.ID} //Query filter to select the revisions of the sheet
+	before := bson.M{"sheetid": sheet.ID, "version": bson.M{"$lt": sheet.Version}}
+	newest := options.FindOne().SetSort(bson.M{"version": -1})
+	ctx, cancel := s.context()
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-	if err != nil { //End if we fail
+	previous := pages.Revision{}
+	err := s.collection("revisions").FindOne(ctx, before, newest).Decode(&previous)
+	if err != nil && err != mongo.ErrNoDocuments {
 		return err
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Get the users collection and try to delete the sheet from the user's array
-	defer cancel()
-	collection := client.Database("CharacterSheets").Collection("users")
-	_, err = collection.UpdateOne(context.TODO(), filterUser, updateUser)
-	if err != nil { //End if we fail
+	revision, ok := newRevision(user, previous.Sheet, sheet, description, time.Now())
+	if !ok {
+		return nil
+	}
+	if _, err = s.collection("revisions").InsertOne(ctx, revision); err != nil {
 		return err
 	}
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second) //Get the sheets collection and try to delete the sheet
-	defer cancel()
-	collection = client.Database("CharacterSheets").Collection("sheets")
-	_, err = collection.DeleteOne(ctx, filterSheets)
-	if err != nil { //End if we fail
+	oldest := pages.Revision{} //The newest revision past the ones we keep
+	opts := options.FindOne().SetSort(bson.M{"version": -1}).SetSkip(MaxRevisions).SetProjection(bson.M{"sheet": 0})
+	err = s.collection("revisions").FindOne(ctx, filter, opts).Decode(&oldest)
+	if err == mongo.ErrNoDocuments { //Not past the limit yet
+		return nil
+	} else if err != nil {
 		return err
 	}
+	filter["version"] = bson.M{"$lte": oldest.Version}
+	_, err = s.collection("revisions").DeleteMany(ctx, filter)
 	return err
 }
@@ -0,0 +1,366 @@
+package db
+
+import (
+	pages "Pages"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//MemoryStore is a Store that keeps everything in memory. Used for tests and local development
+type MemoryStore struct {
+	mu        sync.RWMutex                      //Guards the maps below
+	users     map[string]User                   //Users keyed by username
+	sheets    map[string]map[string]pages.Sheet //Sheets keyed by owner, then by sheet name
+	revisions map[string][]pages.Revision       //Revisions keyed by sheet ID, oldest first
+}
+
+//NewMemoryStore makes an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:     map[string]User{},
+		sheets:    map[string]map[string]pages.Sheet{},
+		revisions: map[string][]pages.Revision{},
+	}
+}
+
+//CheckUser checks if a user exists in the store with the given password
+func (s *MemoryStore) CheckUser(user string, pass string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, ok := s.users[user]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte(pass)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+//CheckUserName checks if the username is already in the store
+func (s *MemoryStore) CheckUserName(user string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.users[user]
+	return ok, nil
+}
+
+//GetSheets lists a given user's character sheets that aren't in the trash, in alphabetical order
+func (s *MemoryStore) GetSheets(user string) ([]pages.SheetInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.users[user]; !ok {
+		return []pages.SheetInfo{}, ErrNotFound
+	}
+	sheets := []pages.SheetInfo{}
+	for _, sheet := range s.sheets[user] {
+		if sheet.Deleted == nil {
+			sheets = append(sheets, pages.SheetInfo{ID: sheet.ID, Name: sheet.Name, CharacterName: sheet.CharacterName})
+		}
+	}
+	sort.Slice(sheets, func(i, j int) bool { return sheets[i].Name < sheets[j].Name })
+	return sheets, nil
+}
+
+//GetSheet retrieves a given user's character sheet
+func (s *MemoryStore) GetSheet(username string, sheetname string) (pages.Sheet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sheet, ok := s.liveSheet(username, sheetname)
+	if !ok {
+		return pages.Sheet{}, ErrNotFound
+	}
+	sheet = copySheet(sheet)
+	sheet.Migrate()
+	return sheet, nil
+}
+
+//GetSheetByID retrieves a given user's character sheet by its ID
+func (s *MemoryStore) GetSheetByID(username string, id string) (pages.Sheet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sheet := range s.sheets[username] {
+		if sheet.ID == id && id != "" && sheet.Deleted == nil {
+			sheet = copySheet(sheet)
+			sheet.Migrate()
+			return sheet, nil
+		}
+	}
+	return pages.Sheet{}, ErrNotFound
+}
+
+//RegisterUser registers a new user
+func (s *MemoryStore) RegisterUser(user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[user.Username]; ok {
+		return ErrExists
+	}
+	s.users[user.Username] = user
+	return nil
+}
+
+//RegisterSheet registers a new character sheet with a user
+func (s *MemoryStore) RegisterSheet(user string, sheet pages.Sheet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[user]; !ok {
+		return ErrNotFound
+	}
+	if existing, ok := s.sheets[user][sheet.Name]; ok && existing.Deleted != nil {
+		return ErrInTrash
+	} else if ok {
+		return ErrExists
+	}
+	sheet.ID = newID()
+	sheet.Owner = user
+	sheet.Version = 0
+	sheet.Deleted = nil
+	if s.sheets[user] == nil {
+		s.sheets[user] = map[string]pages.Sheet{}
+	}
+	s.sheets[user][sheet.Name] = copySheet(sheet)
+	s.addRevision(user, sheet, createdDescription)
+	return nil
+}
+
+//UpdateSheet replaces the user's stored sheet that has the same name as the given sheet
+func (s *MemoryStore) UpdateSheet(user string, sheet pages.Sheet) error {
+	_, err := s.modifySheet(user, sheet.Name, func(stored *pages.Sheet) error {
+		*stored = sheet
+		return nil
+	}, "")
+	return err
+}
+
+//ModifySheet lets change modify a stored sheet, and stores the result. The store is locked while change runs
+func (s *MemoryStore) ModifySheet(user string, name string, change func(*pages.Sheet) error) (pages.Sheet, error) {
+	return s.modifySheet(user, name, change, "")
+}
+
+//Does the work of ModifySheet, saving the revision under the given description. An empty one is made from the changes
+func (s *MemoryStore) modifySheet(user string, name string, change func(*pages.Sheet) error, description string) (pages.Sheet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.liveSheet(user, name)
+	if !ok {
+		return pages.Sheet{}, ErrNotFound
+	}
+	sheet := copySheet(stored)
+	sheet.Migrate()
+	if err := change(&sheet); err != nil {
+		return sheet, err
+	}
+	sheet.ID = stored.ID
+	sheet.Owner = user
+	sheet.Name = name
+	sheet.Version = stored.Version + 1
+	s.sheets[user][name] = copySheet(sheet)
+	s.addRevision(user, sheet, description)
+	return sheet, nil
+}
+
+//RenameSheet gives a sheet a new name, keeping its ID and revisions
+func (s *MemoryStore) RenameSheet(user string, name string, newName string) (pages.Sheet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sheet, ok := s.liveSheet(user, name)
+	if !ok {
+		return pages.Sheet{}, ErrNotFound
+	}
+	if existing, ok := s.sheets[user][newName]; ok && existing.Deleted != nil {
+		return pages.Sheet{}, ErrInTrash
+	} else if ok {
+		return pages.Sheet{}, ErrExists
+	}
+	sheet.Name = newName
+	sheet.Version++
+	delete(s.sheets[user], name)
+	s.sheets[user][newName] = sheet
+	s.addRevision(user, sheet, renamedDescription(name))
+	return copySheet(sheet), nil
+}
+
+//PatchSheet applies a list of changes to a stored sheet, and gives back the updated sheet
+func (s *MemoryStore) PatchSheet(user string, name string, ops []SheetOp) (pages.Sheet, error) {
+	checked, err := checkOps(ops)
+	if err != nil {
+		return pages.Sheet{}, err
+	}
+	return s.ModifySheet(user, name, func(sheet *pages.Sheet) error {
+		return patchSheet(sheet, checked)
+	})
+}
+
+//DeleteSheet moves a sheet to the trash
+func (s *MemoryStore) DeleteSheet(user string, sheet string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trashed, ok := s.liveSheet(user, sheet)
+	if !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	trashed.Deleted = &now
+	s.sheets[user][sheet] = trashed
+	return nil
+}
+
+//ListTrash lists the sheets in a user's trash, latest deleted first
+func (s *MemoryStore) ListTrash(user string) ([]pages.TrashedSheet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.users[user]; !ok {
+		return []pages.TrashedSheet{}, ErrNotFound
+	}
+	trash := []pages.TrashedSheet{}
+	for _, sheet := range s.sheets[user] {
+		if sheet.Deleted != nil {
+			trash = append(trash, pages.TrashedSheet{ID: sheet.ID, Name: sheet.Name, CharacterName: sheet.CharacterName, Deleted: *sheet.Deleted})
+		}
+	}
+	sort.Slice(trash, func(i, j int) bool { return trash[i].Deleted.After(trash[j].Deleted) })
+	return trash, nil
+}
+
+//RestoreSheet takes a sheet out of the trash
+func (s *MemoryStore) RestoreSheet(user string, sheet string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trashed, ok := s.sheets[user][sheet]
+	if !ok || trashed.Deleted == nil {
+		return ErrNotFound
+	}
+	trashed.Deleted = nil
+	s.sheets[user][sheet] = trashed
+	return nil
+}
+
+//PurgeSheet deletes a sheet in the trash for good, along with its revisions
+func (s *MemoryStore) PurgeSheet(user string, sheet string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trashed, ok := s.sheets[user][sheet]
+	if !ok || trashed.Deleted == nil {
+		return ErrNotFound
+	}
+	delete(s.sheets[user], sheet)
+	delete(s.revisions, trashed.ID)
+	return nil
+}
+
+//PurgeTrash purges every user's sheets that went to the trash before the given time, and gives how many were purged
+func (s *MemoryStore) PurgeTrash(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for _, sheets := range s.sheets {
+		for name, sheet := range sheets {
+			if sheet.Deleted != nil && sheet.Deleted.Before(before) {
+				delete(sheets, name)
+				delete(s.revisions, sheet.ID)
+				purged++
+			}
+		}
+	}
+	return purged, nil
+}
+
+//ListRevisions lists the saved revisions of a sheet, newest first and without their sheets
+func (s *MemoryStore) ListRevisions(user string, sheet string) ([]pages.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, ok := s.sheets[user][sheet]
+	if !ok {
+		return []pages.Revision{}, ErrNotFound
+	}
+	stored := s.revisions[found.ID]
+	revisions := make([]pages.Revision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revision := stored[i]
+		revision.Sheet = nil
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+//GetRevision gets one revision of a sheet along with the sheet as it was
+func (s *MemoryStore) GetRevision(user string, sheet string, version int) (pages.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	revision, err := s.findRevision(user, sheet, version)
+	if err != nil {
+		return revision, err
+	}
+	copied := copySheet(*revision.Sheet)
+	copied.Migrate()
+	revision.Sheet = &copied
+	return revision, nil
+}
+
+//RestoreRevision puts an older revision of a sheet back in place, as a new revision
+func (s *MemoryStore) RestoreRevision(user string, sheet string, version int) (pages.Sheet, error) {
+	return s.modifySheet(user, sheet, func(stored *pages.Sheet) error {
+		revision, err := s.findRevision(user, sheet, version) //The store is already locked by modifySheet
+		if err != nil {
+			return err
+		}
+		restoreRevision(stored, revision)
+		return nil
+	}, restoredDescription(version))
+}
+
+//Finds a stored sheet that isn't in the trash. The caller has to hold the lock
+func (s *MemoryStore) liveSheet(user string, name string) (pages.Sheet, bool) {
+	sheet, ok := s.sheets[user][name]
+	return sheet, ok && sheet.Deleted == nil
+}
+
+//Finds a stored revision of a sheet by the sheet's name. The caller has to hold the lock
+func (s *MemoryStore) findRevision(user string, sheet string, version int) (pages.Revision, error) {
+	found, ok := s.sheets[user][sheet]
+	if !ok {
+		return pages.Revision{}, ErrNotFound
+	}
+	for _, revision := range s.revisions[found.ID] {
+		if revision.Version == version {
+			return revision, nil
+		}
+	}
+	return pages.Revision{}, ErrNotFound
+}
+
+//Saves a revision of a sheet that was just written, dropping the oldest ones past MaxRevisions. The caller has to hold the lock
+func (s *MemoryStore) addRevision(user string, sheet pages.Sheet, description string) {
+	stored := s.revisions[sheet.ID]
+	var previous *pages.Sheet
+	if len(stored) > 0 {
+		previous = stored[len(stored)-1].Sheet
+	}
+	revision, ok := newRevision(user, previous, sheet, description, time.Now())
+	if !ok {
+		return
+	}
+	stored = append(stored, revision)
+	if len(stored) > MaxRevisions {
+		stored = append([]pages.Revision{}, stored[len(stored)-MaxRevisions:]...)
+	}
+	s.revisions[sheet.ID] = stored
+}
+
+//Close does nothing, as the memory store holds no outside resources
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+//Makes a deep copy of a sheet, so the stored sheets never share lists with the ones handed out
+func copySheet(sheet pages.Sheet) pages.Sheet {
+	copied := pages.Sheet{}
+	data, _ := json.Marshal(sheet)
+	json.Unmarshal(data, &copied)
+	return copied
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	pages "Pages"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//Makes a memory store with one user, bob, who has the password "pw"
+func testStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("pw"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryStore()
+	if err := store.RegisterUser(User{Username: "bob", Password: string(hash)}); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+//Registers a sheet with bob, failing the test if it can't
+func registerSheet(t *testing.T, store *MemoryStore, sheet pages.Sheet) pages.Sheet {
+	t.Helper()
+	if err := store.RegisterSheet("bob", sheet); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.GetSheet("bob", sheet.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestMemoryUsers(t *testing.T) {
+	store := testStore(t)
+	if ok, err := store.CheckUser("bob", "pw"); !ok || err != nil {
+		t.Errorf("CheckUser with the right password gave %v, %v", ok, err)
+	}
+	if ok, _ := store.CheckUser("bob", "wrong"); ok {
+		t.Error("CheckUser accepted a wrong password")
+	}
+	if _, err := store.CheckUser("alice", "pw"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CheckUser of a missing user gave %v, want ErrNotFound", err)
+	}
+	if taken, _ := store.CheckUserName("bob"); !taken {
+		t.Error("CheckUserName didn't find bob")
+	}
+	if err := store.RegisterUser(User{Username: "bob"}); !errors.Is(err, ErrExists) {
+		t.Errorf("registering bob twice gave %v, want ErrExists", err)
+	}
+}
+
+func TestMemoryRegisterSheet(t *testing.T) {
+	store := testStore(t)
+	sheet := registerSheet(t, store, pages.Sheet{Name: "Test", CharacterName: "Bo", Owner: "someone else", Version: 7})
+	if sheet.Owner != "bob" {
+		t.Errorf("owner is %q, want bob", sheet.Owner)
+	}
+	if sheet.Version != 0 {
+		t.Errorf("the sheet starts at version %d, want 0", sheet.Version)
+	}
+	if sheet.ID == "" {
+		t.Error("the sheet wasn't given an ID")
+	}
+	if byID, err := store.GetSheetByID("bob", sheet.ID); err != nil || byID.Name != "Test" {
+		t.Errorf("GetSheetByID gave %q, %v", byID.Name, err)
+	}
+	if err := store.RegisterSheet("bob", pages.Sheet{Name: "Test"}); !errors.Is(err, ErrExists) {
+		t.Errorf("registering Test twice gave %v, want ErrExists", err)
+	}
+	if err := store.RegisterSheet("alice", pages.Sheet{Name: "Test"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("registering with a missing user gave %v, want ErrNotFound", err)
+	}
+	registerSheet(t, store, pages.Sheet{Name: "Alpha"})
+	list, err := store.GetSheets("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "Alpha" || list[1].Name != "Test" || list[1].CharacterName != "Bo" {
+		t.Errorf("GetSheets gave %+v", list)
+	}
+}
+
+func TestMemorySheetsAreCopied(t *testing.T) {
+	store := testStore(t)
+	sheet := registerSheet(t, store, pages.Sheet{Name: "Test", Languages: []string{"Common"}})
+	sheet.Languages[0] = "Elvish"
+	stored, _ := store.GetSheet("bob", "Test")
+	if stored.Languages[0] != "Common" {
+		t.Error("changing a sheet that was handed out changed the stored sheet")
+	}
+}
+
+func TestMemoryModifySheet(t *testing.T) {
+	store := testStore(t)
+	sheet := registerSheet(t, store, pages.Sheet{Name: "Test", CurrentExpirience: 10})
+	updated, err := store.ModifySheet("bob", "Test", func(stored *pages.Sheet) error {
+		stored.CurrentExpirience += 5
+		stored.Name = "Renamed" //Ignored, names change through RenameSheet
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.CurrentExpirience != 15 || updated.Name != "Test" || updated.ID != sheet.ID || updated.Version != sheet.Version+1 {
+		t.Errorf("ModifySheet gave %+v", updated)
+	}
+	failed := errors.New("failed")
+	if _, err := store.ModifySheet("bob", "Test", func(stored *pages.Sheet) error {
+		stored.CurrentExpirience = 0
+		return failed
+	}); err != failed {
+		t.Errorf("ModifySheet gave %v, want the error of the change", err)
+	}
+	if stored, _ := store.GetSheet("bob", "Test"); stored.CurrentExpirience != 15 {
+		t.Errorf("a failed change was stored, exp is %d", stored.CurrentExpirience)
+	}
+}
+
+func TestMemoryUpdateSheet(t *testing.T) {
+	store := testStore(t)
+	sheet := registerSheet(t, store, pages.Sheet{Name: "Test", CurrentExpirience: 10})
+	if err := store.UpdateSheet("bob", pages.Sheet{Name: "Test", CurrentExpirience: 20, Owner: "someone else", Version: 7}); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := store.GetSheet("bob", "Test")
+	if updated.CurrentExpirience != 20 || updated.ID != sheet.ID || updated.Owner != "bob" || updated.Version != sheet.Version+1 {
+		t.Errorf("UpdateSheet stored %+v", updated)
+	}
+	if err := store.UpdateSheet("bob", pages.Sheet{Name: "Missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a missing sheet gave %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryTrash(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, pages.Sheet{Name: "Test"})
+	if err := store.DeleteSheet("bob", "Test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetSheet("bob", "Test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a trashed sheet was found, %v", err)
+	}
+	if err := store.RegisterSheet("bob", pages.Sheet{Name: "Test"}); !errors.Is(err, ErrInTrash) {
+		t.Errorf("taking the name of a trashed sheet gave %v, want ErrInTrash", err)
+	}
+	if trash, _ := store.ListTrash("bob"); len(trash) != 1 || trash[0].Name != "Test" {
+		t.Errorf("ListTrash gave %+v", trash)
+	}
+	if err := store.RestoreSheet("bob", "Test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetSheet("bob", "Test"); err != nil {
+		t.Errorf("the restored sheet wasn't found, %v", err)
+	}
+	store.DeleteSheet("bob", "Test")
+	if purged, _ := store.PurgeTrash(time.Now().Add(-time.Hour)); purged != 0 {
+		t.Errorf("purged %d sheets deleted after the cutoff", purged)
+	}
+	if purged, _ := store.PurgeTrash(time.Now().Add(time.Hour)); purged != 1 {
+		t.Errorf("purged %d sheets, want 1", purged)
+	}
+	if trash, _ := store.ListTrash("bob"); len(trash) != 0 {
+		t.Errorf("the trash still holds %+v", trash)
+	}
+}
+
+func TestMemoryRevisions(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, pages.Sheet{Name: "Test", CurrentExpirience: 10})
+	store.ModifySheet("bob", "Test", func(stored *pages.Sheet) error {
+		stored.CurrentExpirience = 20
+		return nil
+	})
+	if _, err := store.RenameSheet("bob", "Test", "Renamed"); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := store.ListRevisions("bob", "Renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || revisions[0].Description != renamedDescription("Test") || revisions[2].Description != createdDescription {
+		t.Fatalf("ListRevisions gave %+v", revisions)
+	}
+	restored, err := store.RestoreRevision("bob", "Renamed", revisions[2].Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.CurrentExpirience != 10 || restored.Name != "Renamed" {
+		t.Errorf("restoring the first revision gave exp %d and name %q", restored.CurrentExpirience, restored.Name)
+	}
+}
+
+func TestMemoryRevisionsFollowTheSheet(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, pages.Sheet{Name: "Test", CurrentExpirience: 10})
+	store.RenameSheet("bob", "Test", "Renamed")
+	registerSheet(t, store, pages.Sheet{Name: "Test", CurrentExpirience: 30}) //Takes the old name
+	if revisions, _ := store.ListRevisions("bob", "Test"); len(revisions) != 1 || revisions[0].Description != createdDescription {
+		t.Errorf("a new sheet with an old name got the history %+v", revisions)
+	}
+	store.DeleteSheet("bob", "Test")
+	if err := store.PurgeSheet("bob", "Test"); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, _ := store.ListRevisions("bob", "Renamed"); len(revisions) != 2 {
+		t.Errorf("purging a sheet with the old name left %d revisions of the renamed one, want 2", len(revisions))
+	}
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	pages "Pages"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//ErrBadPatch is returned when a partial sheet update can't be applied to a sheet
+var ErrBadPatch = errors.New("invalid sheet update")
+
+//SheetOp is a single change to one field or list entry of a stored sheet
+type SheetOp struct {
+	Op    string      `json:"op"`    //"set" replaces the value, "inc" adds to a number and "push" appends to a list
+	Path  string      `json:"path"`  //Dot separated path using the sheet's json names, like "inventory.3.amount"
+	Value interface{} `json:"value"` //The value to set, add or append
+}
+
+//Fields that identify a sheet, are computed from other fields or follow rules of their own, and so can't be changed by a partial update
+var lockedFields = map[string]bool{"id": true, "owner": true, "name": true, "version": true, "class": true, "level": true, "hitDie": true,
+	"health": true, "hitPoints": true, "history": true, "exhaustion": true, "conditions": true, "money": true, "ledger": true,
+	"rolls": true, "deleted": true}
+
+//Fields whose entries are computed from other fields, with the one part of them a partial update can change
+var computedFields = map[string]string{"spellSlots": "used", "pactSlots": "used"}
+
+//Checks that an op fits the sheet layout, and converts its value to the type stored at the path
+func checkOp(op SheetOp) (SheetOp, error) {
+	segments := strings.Split(op.Path, ".")
+	if lockedFields[segments[0]] {
+		return op, fmt.Errorf("%w: %s can't be changed", ErrBadPatch, segments[0])
+	}
+	if part, ok := computedFields[segments[0]]; ok && segments[len(segments)-1] != part { //The rest follows the class levels
+		return op, fmt.Errorf("%w: only the %s part of %s can be changed", ErrBadPatch, part, segments[0])
+	}
+	t := reflect.TypeOf(pages.Sheet{})
+	for _, segment := range segments {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := jsonField(t, segment)
+			if !ok {
+				return op, fmt.Errorf("%w: unknown field %s", ErrBadPatch, op.Path)
+			}
+			t = field.Type
+		case reflect.Slice:
+			if index, err := strconv.Atoi(segment); err != nil || index < 0 {
+				return op, fmt.Errorf("%w: %s is not a list index in %s", ErrBadPatch, segment, op.Path)
+			}
+			t = t.Elem()
+		default:
+			return op, fmt.Errorf("%w: unknown field %s", ErrBadPatch, op.Path)
+		}
+	}
+	switch op.Op {
+	case "set":
+	case "inc":
+		if t.Kind() != reflect.Int {
+			return op, fmt.Errorf("%w: %s is not a number", ErrBadPatch, op.Path)
+		}
+	case "push":
+		if t.Kind() != reflect.Slice {
+			return op, fmt.Errorf("%w: %s is not a list", ErrBadPatch, op.Path)
+		}
+		t = t.Elem()
+	default:
+		return op, fmt.Errorf("%w: unknown operation %q", ErrBadPatch, op.Op)
+	}
+	value, err := convertValue(t, op.Value)
+	if err != nil {
+		return op, fmt.Errorf("%w: bad value for %s: %v", ErrBadPatch, op.Path, err)
+	}
+	op.Value = value
+	return op, nil
+}
+
+//Finds the struct field that has the given json name
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+//Turns a value decoded from json into the given type, by running it through json again
+func convertValue(t reflect.Type, value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	converted := reflect.New(t)
+	if err = json.Unmarshal(data, converted.Interface()); err != nil {
+		return nil, err
+	}
+	return converted.Elem().Interface(), nil
+}
+
+//Checks a list of ops, giving them with their values converted
+func checkOps(ops []SheetOp) ([]SheetOp, error) {
+	checked := make([]SheetOp, len(ops))
+	for i, op := range ops {
+		var err error
+		if checked[i], err = checkOp(op); err != nil {
+			return nil, err
+		}
+	}
+	return checked, nil
+}
+
+//Applies checked ops to a sheet, in order, and checks that they didn't break the rules the sheet follows. Problems the
+//sheet already had, like a missing race on a sheet from before validation, don't stop the ops
+func patchSheet(sheet *pages.Sheet, ops []SheetOp) error {
+	before := sheet.Validate()
+	if err := applyOps(sheet, ops); err != nil {
+		return err
+	}
+	sheet.SyncClasses() //The ops may have changed class levels
+	problems := []string{}
+	for path, message := range sheet.Validate() {
+		if before[path] != message {
+			problems = append(problems, path+": "+message)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrBadPatch, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+//Applies checked ops to a sheet in memory
+func applyOps(sheet *pages.Sheet, ops []SheetOp) error {
+	for _, op := range ops {
+		target := reflect.ValueOf(sheet).Elem()
+		for _, segment := range strings.Split(op.Path, ".") {
+			if target.Kind() == reflect.Slice {
+				index, _ := strconv.Atoi(segment)
+				if index >= target.Len() {
+					return fmt.Errorf("%w: %s is out of range", ErrBadPatch, op.Path)
+				}
+				target = target.Index(index)
+			} else {
+				field, _ := jsonField(target.Type(), segment)
+				target = target.FieldByIndex(field.Index)
+			}
+		}
+		value := reflect.ValueOf(op.Value)
+		switch op.Op {
+		case "set":
+			target.Set(value)
+		case "inc":
+			target.SetInt(target.Int() + value.Int())
+		case "push":
+			target.Set(reflect.Append(target, value))
+		}
+	}
+	return nil
+}
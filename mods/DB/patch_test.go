@@ -0,0 +1,83 @@
+package db
+
+import (
+	pages "Pages"
+	"errors"
+	"testing"
+)
+
+//Makes a sheet that passes validation
+func validSheet(name string) pages.Sheet {
+	return pages.Sheet{
+		Name:          name,
+		CharacterName: "Bo",
+		Race:          "Human",
+		Allignment:    "N",
+		Size:          "Medium",
+		Classes:       []pages.ClassLevel{{Name: "Fighter", Level: 2, HitDie: "1d10"}},
+		Scores:        pages.Abilities{Strength: 10, Dexterity: 10, Constitution: 10, Intelligence: 10, Wisdom: 10, Charisma: 10},
+		HitPoints:     pages.HitPoints{Max: 12, Current: 12},
+		Inventory:     []pages.Item{{Name: "Rope", Amount: 1}},
+	}
+}
+
+func TestPatchSheet(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, validSheet("Test"))
+	sheet, err := store.PatchSheet("bob", "Test", []SheetOp{
+		{Op: "inc", Path: "inventory.0.amount", Value: 5},
+		{Op: "inc", Path: "inventory.0.amount", Value: 5}, //Ops on the same path all count
+		{Op: "set", Path: "classes.0.level", Value: 3},
+		{Op: "push", Path: "languages", Value: "Elvish"}, //Pushing to a list that was left out
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sheet.Inventory[0].Amount != 11 {
+		t.Errorf("two incs of 5 on 1 gave %d, want 11", sheet.Inventory[0].Amount)
+	}
+	if sheet.Level != 3 {
+		t.Errorf("the level wasn't synced with the classes, it is %d", sheet.Level)
+	}
+	if len(sheet.Languages) != 1 || sheet.Languages[0] != "Elvish" {
+		t.Errorf("languages are %v", sheet.Languages)
+	}
+}
+
+func TestPatchSheetRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []SheetOp
+	}{
+		{"locked field", []SheetOp{{Op: "set", Path: "level", Value: 5}}},
+		{"unknown field", []SheetOp{{Op: "set", Path: "mana", Value: 5}}},
+		{"inc on text", []SheetOp{{Op: "inc", Path: "race", Value: 1}}},
+		{"out of range", []SheetOp{{Op: "inc", Path: "inventory.4.amount", Value: 1}}},
+		{"negative class level", []SheetOp{{Op: "set", Path: "classes.0.level", Value: -4}}},
+		{"negative amount", []SheetOp{{Op: "inc", Path: "inventory.0.amount", Value: -2}}},
+		{"bad alignment", []SheetOp{{Op: "set", Path: "allignment", Value: "Chaotic"}}},
+		{"coins", []SheetOp{{Op: "inc", Path: "money.gp", Value: 7}}}, //Coins change through /coins, which keeps the ledger
+		{"slot max", []SheetOp{{Op: "set", Path: "spellSlots.0.max", Value: 9}}},
+		{"pact slots", []SheetOp{{Op: "set", Path: "pactSlots", Value: map[string]int{"level": 5, "max": 4}}}},
+	}
+	for _, test := range tests {
+		store := testStore(t)
+		registerSheet(t, store, validSheet("Test"))
+		if _, err := store.PatchSheet("bob", "Test", test.ops); !errors.Is(err, ErrBadPatch) {
+			t.Errorf("%s: PatchSheet gave %v, want ErrBadPatch", test.name, err)
+		}
+		if stored, _ := store.GetSheet("bob", "Test"); stored.Version != 0 || stored.Level != 2 || stored.Inventory[0].Amount != 1 {
+			t.Errorf("%s: a rejected patch changed the sheet", test.name)
+		}
+	}
+}
+
+func TestPatchSheetKeepsOldProblems(t *testing.T) {
+	store := testStore(t)
+	old := validSheet("Test")
+	old.Race = "" //Saved before races were required
+	registerSheet(t, store, old)
+	if _, err := store.PatchSheet("bob", "Test", []SheetOp{{Op: "inc", Path: "currentExpirience", Value: 50}}); err != nil {
+		t.Errorf("a problem the sheet already had stopped the patch: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	pages "Pages"
+	"errors"
+	"testing"
+)
+
+func TestMemoryRenameSheet(t *testing.T) {
+	store := testStore(t)
+	sheet := registerSheet(t, store, pages.Sheet{Name: "Test"})
+	registerSheet(t, store, pages.Sheet{Name: "Taken"})
+	renamed, err := store.RenameSheet("bob", "Test", "Renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "Renamed" || renamed.ID != sheet.ID || renamed.Version != sheet.Version+1 {
+		t.Errorf("RenameSheet gave %+v", renamed)
+	}
+	if _, err := store.GetSheet("bob", "Test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("the old name still finds a sheet: %v", err)
+	}
+	if byID, err := store.GetSheetByID("bob", sheet.ID); err != nil || byID.Name != "Renamed" {
+		t.Errorf("the ID finds %q, %v", byID.Name, err)
+	}
+	tests := []struct {
+		name    string
+		from    string
+		newName string
+		want    error
+	}{
+		{"taken name", "Renamed", "Taken", ErrExists},
+		{"missing sheet", "Missing", "Free", ErrNotFound},
+	}
+	for _, test := range tests {
+		if _, err := store.RenameSheet("bob", test.from, test.newName); !errors.Is(err, test.want) {
+			t.Errorf("%s: RenameSheet gave %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestMemorySheetIDs(t *testing.T) {
+	store := testStore(t)
+	first := registerSheet(t, store, pages.Sheet{Name: "First", ID: "chosen"})
+	second := registerSheet(t, store, pages.Sheet{Name: "Second"})
+	if first.ID == "chosen" || first.ID == second.ID {
+		t.Errorf("the sheets got the IDs %q and %q", first.ID, second.ID)
+	}
+	store.DeleteSheet("bob", "First")
+	if _, err := store.GetSheetByID("bob", first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a trashed sheet was found by its ID: %v", err)
+	}
+	store.RegisterUser(User{Username: "alice"})
+	if _, err := store.GetSheetByID("alice", second.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("another user found bob's sheet by its ID: %v", err)
+	}
+	if _, err := store.GetSheetByID("bob", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("an empty ID gave %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	pages "Pages"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//How long Repair may take. It goes through every user and sheet, so it gets longer than a single store call
+const repairTimeout = 5 * time.Minute
+
+//RepairReport lists what Repair found and fixed
+type RepairReport struct {
+	Users            int      //Users checked
+	Sheets           int      //Sheets checked, not counting the ones in the trash
+	Dangling         []string //"owner/name" of names in a user's old sheet list that had no sheet. Dropped along with the list
+	Trashed          []string //"owner/name" of sheets missing from their owner's old sheet list, or with no owner. Moved to the trash
+	ListsDropped     int      //Users whose old sheet list was removed
+	IDsAdded         int      //Sheets that were given an ID
+	Renamed          []string //"owner/name to new name" of sheets renamed because an older sheet of their owner had the same name
+	RevisionsKeyed   int      //Revisions that were kept by sheet name, and now are by the ID of their sheet
+	RevisionsDropped int      //Revisions kept by the name of a sheet that no longer exists
+	SlotsStored      int      //Sheets that were saved before slots were tracked, and were given the slots of their class levels
+}
+
+//Repair fixes the mismatches left by versions that kept a list of sheet names on every user next to the sheets themselves.
+//A name on a list without a sheet was left by a failed create, and is dropped. A sheet that isn't on its owner's list was
+//left by a failed delete, so it is moved to the trash, where it can still be restored. Sheets whose owner doesn't exist go
+//to the trash as well. The lists are removed afterwards, as sheets are now found through their owner. It has to run before
+//a server without the lists takes writes, as the sheets that server creates aren't on any list. Last, every sheet gets an ID
+//and a name of its own, its revisions are kept by that ID, and the indexes are built. Sheets get their spell slots set by
+//slots, which gives false if they were already right. Running it again is safe
+func (s *MongoStore) Repair(slots func(*pages.Sheet) bool) (RepairReport, error) {
+	report := RepairReport{Dangling: []string{}, Trashed: []string{}, Renamed: []string{}}
+	var users []struct { //Help struct that saves the users retrieved from the database
+		Username string   `json:"username"`
+		Sheets   []string `json:"sheets"`
+	}
+	var sheets []sheetName //The sheets in use
+	ctx, cancel := context.WithTimeout(context.Background(), repairTimeout)
+	defer cancel()
+	cursor, err := s.collection("users").Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"username": 1, "sheets": 1}))
+	if err == nil {
+		err = cursor.All(ctx, &users)
+	}
+	if err != nil {
+		return report, err
+	}
+	cursor, err = s.collection("sheets").Find(ctx, bson.M{"deleted": nil}, options.Find().SetProjection(bson.M{"owner": 1, "name": 1}))
+	if err == nil {
+		err = cursor.All(ctx, &sheets)
+	}
+	if err != nil {
+		return report, err
+	}
+	report.Users, report.Sheets = len(users), len(sheets)
+	lists := map[string][]string{} //Each user's list, keyed by username
+	for _, user := range users {
+		lists[user.Username] = user.Sheets
+	}
+	trashed, dangling := compareLists(lists, sheets)
+	now := time.Now()
+	for _, sheet := range trashed {
+		filter := bson.M{"owner": sheet.Owner, "name": sheet.Name, "deleted": nil}
+		if _, err = s.collection("sheets").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"deleted": now}}); err != nil {
+			return report, err
+		}
+		report.Trashed = append(report.Trashed, sheet.Owner+"/"+sheet.Name)
+	}
+	report.Dangling = append(report.Dangling, dangling...)
+	result, err := s.collection("users").UpdateMany(ctx, bson.M{"sheets": bson.M{"$exists": true}}, bson.M{"$unset": bson.M{"sheets": ""}})
+	if err != nil {
+		return report, err
+	}
+	report.ListsDropped = int(result.ModifiedCount)
+	if err = s.repairSheets(ctx, &report); err != nil {
+		return report, err
+	}
+	if err = s.repairSlots(ctx, &report, slots); err != nil {
+		return report, err
+	}
+	return report, s.ensureIndexes(ctx)
+}
+
+//The owner and name of a sheet, as Repair reads them
+type sheetName struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+}
+
+//Compares the old sheet lists of the users, keyed by username, with the sheets in use. Gives the sheets that have to go to
+//the trash, as they aren't on their owner's list or have no owner, and the "owner/name" of the listed names without a sheet.
+//A user whose list is nil had it removed already, and keeps all of their sheets
+func compareLists(lists map[string][]string, sheets []sheetName) ([]sheetName, []string) {
+	trashed := []sheetName{}
+	stored := map[sheetName]bool{}
+	for _, sheet := range sheets {
+		stored[sheet] = true
+		list, hasOwner := lists[sheet.Owner]
+		if !hasOwner || (list != nil && !containsName(list, sheet.Name)) {
+			trashed = append(trashed, sheet)
+		}
+	}
+	dangling := []string{}
+	for user, list := range lists {
+		for _, name := range list {
+			if key := (sheetName{Owner: user, Name: name}); !stored[key] {
+				dangling = append(dangling, user+"/"+name)
+				stored[key] = true //Listed twice, but only dropped once
+			}
+		}
+	}
+	sort.Strings(dangling)
+	return trashed, dangling
+}
+
+//Checks if a list of sheet names holds the given name
+func containsName(names []string, name string) bool {
+	for _, listed := range names {
+		if listed == name {
+			return true
+		}
+	}
+	return false
+}
+
+//Gives an ID to every sheet without one, and renames the sheets that share their name with an older sheet of the same
+//owner, like "Bob (2)", so the unique indexes can be built. Revisions kept by name go to the oldest sheet with the name,
+//and the ones whose sheet is gone are dropped
+func (s *MongoStore) repairSheets(ctx context.Context, report *RepairReport) error {
+	var sheets []struct { //Help struct that saves the sheets retrieved from the database, trashed ones included
+		Key   primitive.ObjectID `bson:"_id"`
+		ID    string             `bson:"id"`
+		Owner string             `bson:"owner"`
+		Name  string             `bson:"name"`
+	}
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetProjection(bson.M{"id": 1, "owner": 1, "name": 1}) //Oldest first, so the oldest sheet keeps its name
+	cursor, err := s.collection("sheets").Find(ctx, bson.M{}, opts)
+	if err == nil {
+		err = cursor.All(ctx, &sheets)
+	}
+	if err != nil {
+		return err
+	}
+	names := make([]sheetName, len(sheets))
+	for i, sheet := range sheets {
+		names[i] = sheetName{Owner: sheet.Owner, Name: sheet.Name}
+	}
+	renames := uniqueNames(names)
+	for i, sheet := range sheets {
+		set := bson.M{}
+		if sheet.ID == "" {
+			sheet.ID = newID()
+			set["id"] = sheet.ID
+			report.IDsAdded++
+		}
+		if name, ok := renames[i]; ok {
+			set["name"] = name
+			report.Renamed = append(report.Renamed, sheet.Owner+"/"+sheet.Name+" to "+name)
+		} else {
+			filter := bson.M{"owner": sheet.Owner, "name": sheet.Name, "sheetid": nil}
+			result, err := s.collection("revisions").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"sheetid": sheet.ID}})
+			if err != nil {
+				return err
+			}
+			report.RevisionsKeyed += int(result.ModifiedCount)
+		}
+		if len(set) == 0 {
+			continue
+		}
+		if _, err = s.collection("sheets").UpdateOne(ctx, bson.M{"_id": sheet.Key}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+	}
+	result, err := s.collection("revisions").DeleteMany(ctx, bson.M{"sheetid": nil})
+	if err != nil {
+		return err
+	}
+	report.RevisionsDropped = int(result.DeletedCount)
+	return nil
+}
+
+//Gives new names, like "Bob (2)", to the sheets that share their owner and name with an earlier sheet in the list, keyed
+//by their index. A new name is never one that another sheet of the owner has
+func uniqueNames(sheets []sheetName) map[int]string {
+	taken := map[sheetName]bool{}
+	for _, sheet := range sheets {
+		taken[sheet] = true
+	}
+	renames := map[int]string{}
+	seen := map[sheetName]bool{}
+	for i, sheet := range sheets {
+		if !seen[sheet] {
+			seen[sheet] = true
+			continue
+		}
+		name := sheet
+		for n := 2; taken[name]; n++ {
+			name.Name = fmt.Sprintf("%s (%d)", sheet.Name, n)
+		}
+		taken[name] = true
+		renames[i] = name.Name
+	}
+	return renames
+}
+
+//Stores the slots of the sheets saved before slots were tracked, so they can be used through partial updates, which
+//can't change the slot table itself
+func (s *MongoStore) repairSlots(ctx context.Context, report *RepairReport, slots func(*pages.Sheet) bool) error {
+	var sheets []pages.Sheet
+	cursor, err := s.collection("sheets").Find(ctx, bson.M{})
+	if err == nil {
+		err = cursor.All(ctx, &sheets)
+	}
+	if err != nil {
+		return err
+	}
+	for _, sheet := range sheets {
+		sheet.Migrate() //The slots follow the class list, which older sheets only get here
+		if !slots(&sheet) {
+			continue
+		}
+		set := bson.M{"spellslots": sheet.SpellSlots, "pactslots": sheet.PactSlots}
+		if _, err = s.collection("sheets").UpdateOne(ctx, bson.M{"id": sheet.ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+		report.SlotsStored++
+	}
+	return nil
+}
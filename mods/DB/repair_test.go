@@ -0,0 +1,45 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareLists(t *testing.T) {
+	lists := map[string][]string{
+		"bob":   {"Listed", "Gone", "Gone"},
+		"alice": nil, //Repaired already
+	}
+	sheets := []sheetName{
+		{Owner: "bob", Name: "Listed"},
+		{Owner: "bob", Name: "Unlisted"},
+		{Owner: "alice", Name: "Anything"},
+		{Owner: "carol", Name: "Orphan"},
+	}
+	trashed, dangling := compareLists(lists, sheets)
+	if want := []sheetName{{Owner: "bob", Name: "Unlisted"}, {Owner: "carol", Name: "Orphan"}}; !reflect.DeepEqual(trashed, want) {
+		t.Errorf("compareLists trashed %+v, want %+v", trashed, want)
+	}
+	if want := []string{"bob/Gone"}; !reflect.DeepEqual(dangling, want) {
+		t.Errorf("compareLists dropped %q, want %q", dangling, want)
+	}
+	trashed, dangling = compareLists(map[string][]string{"bob": {}}, []sheetName{{Owner: "bob", Name: "Test"}})
+	if len(trashed) != 1 || len(dangling) != 0 {
+		t.Errorf("an empty list trashed %+v and dropped %q", trashed, dangling)
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	sheets := []sheetName{
+		{Owner: "bob", Name: "Bob"},
+		{Owner: "bob", Name: "Bob"},
+		{Owner: "bob", Name: "Bob (2)"}, //Keeps its name, so the copy above skips it
+		{Owner: "alice", Name: "Bob"},
+		{Owner: "bob", Name: "Bob"},
+		{Owner: "bob", Name: "Other"},
+	}
+	want := map[int]string{1: "Bob (3)", 4: "Bob (4)"}
+	if renames := uniqueNames(sheets); !reflect.DeepEqual(renames, want) {
+		t.Errorf("uniqueNames gave %v, want %v", renames, want)
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	pages "Pages"
+	"strconv"
+	"time"
+)
+
+//MaxRevisions is how many revisions are kept for each sheet. The oldest ones are dropped first
+const MaxRevisions = 200
+
+//Description of the revision saved when a sheet is created
+const createdDescription = "Created the sheet"
+
+//Makes the revision for a sheet that was just written. Previous is the sheet of the latest revision, or nil if there is none.
+//Gives false if nothing tracked changed since the previous revision. An empty description is made from the changes
+func newRevision(user string, previous *pages.Sheet, sheet pages.Sheet, description string, now time.Time) (pages.Revision, bool) {
+	if previous != nil {
+		changes := pages.Diff(*previous, sheet)
+		if len(changes) == 0 { //Like a dice roll, which only adds to the roll log
+			return pages.Revision{}, false
+		}
+		if description == "" {
+			description = pages.DescribeChanges(changes)
+		}
+	} else if description == "" { //A sheet stored before revisions were kept
+		description = "Started the revision history"
+	}
+	copied := copySheet(sheet)
+	return pages.Revision{Owner: user, SheetID: sheet.ID, Name: sheet.Name, Version: sheet.Version, Date: now, Description: description, Sheet: &copied}, true
+}
+
+//Describes the revision saved when an older one is restored
+func restoredDescription(version int) string {
+	return "Restored version " + strconv.Itoa(version)
+}
+
+//Describes the revision saved when a sheet is renamed
+func renamedDescription(oldName string) string {
+	return "Renamed from " + oldName
+}
+
+//Puts the sheet of a revision in place of a stored sheet. The roll log isn't part of the history, so it is kept
+func restoreRevision(sheet *pages.Sheet, revision pages.Revision) {
+	rolls := sheet.Rolls
+	*sheet = copySheet(*revision.Sheet)
+	sheet.Migrate()
+	sheet.Rolls = rolls
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	pages "Pages"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewRevision(t *testing.T) {
+	now := time.Now()
+	sheet := pages.Sheet{ID: "abc", Name: "Test", Version: 2, CurrentExpirience: 10, Languages: []string{"Common"}}
+	started, ok := newRevision("bob", nil, sheet, "", now)
+	if !ok || started.Description != "Started the revision history" || started.SheetID != "abc" || started.Version != 2 || !started.Date.Equal(now) {
+		t.Errorf("the first revision is %+v", started)
+	}
+	rolled := sheet
+	rolled.Version = 3
+	rolled.Rolls = []pages.RollRecord{{Notation: "1d20"}}
+	if _, ok := newRevision("bob", &sheet, rolled, "", now); ok {
+		t.Error("a roll, which changes nothing tracked, made a revision")
+	}
+	changed := rolled
+	changed.CurrentExpirience = 20
+	if revision, ok := newRevision("bob", &sheet, changed, "", now); !ok || revision.Description != "Changed currentExpirience" {
+		t.Errorf("a change of exp made %+v", revision)
+	}
+	if revision, _ := newRevision("bob", &sheet, changed, "Leveled up", now); revision.Description != "Leveled up" {
+		t.Errorf("the description given was replaced by %q", revision.Description)
+	}
+	sheet.Languages[0] = "Elvish"
+	if started.Sheet.Languages[0] != "Common" {
+		t.Error("the revision shares its sheet with the stored one")
+	}
+}
+
+func TestMemoryRevisionLimit(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, pages.Sheet{Name: "Test"})
+	for i := 1; i <= MaxRevisions+5; i++ {
+		store.ModifySheet("bob", "Test", func(stored *pages.Sheet) error {
+			stored.CurrentExpirience = i
+			return nil
+		})
+	}
+	revisions, err := store.ListRevisions("bob", "Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != MaxRevisions || revisions[0].Version != MaxRevisions+5 || revisions[MaxRevisions-1].Version != 6 {
+		t.Errorf("kept %d revisions, from version %d to %d", len(revisions), revisions[len(revisions)-1].Version, revisions[0].Version)
+	}
+	if revisions[0].Sheet != nil {
+		t.Error("the listed revisions hold their sheets")
+	}
+	if _, err := store.GetRevision("bob", "Test", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("getting a dropped revision gave %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryRestoreRevision(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, pages.Sheet{Name: "Test", CurrentExpirience: 10})
+	store.ModifySheet("bob", "Test", func(stored *pages.Sheet) error {
+		stored.CurrentExpirience = 99
+		stored.Rolls = []pages.RollRecord{{Notation: "1d20", Total: 12}}
+		return nil
+	})
+	restored, err := store.RestoreRevision("bob", "Test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.CurrentExpirience != 10 || len(restored.Rolls) != 1 || restored.Version != 2 {
+		t.Errorf("restoring version 0 gave exp %d, %d rolls and version %d", restored.CurrentExpirience, len(restored.Rolls), restored.Version)
+	}
+	revisions, _ := store.ListRevisions("bob", "Test")
+	if revisions[0].Description != restoredDescription(0) {
+		t.Errorf("the restore was saved as %q", revisions[0].Description)
+	}
+	if _, err := store.RestoreRevision("bob", "Test", 7); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring a missing version gave %v, want ErrNotFound", err)
+	}
+	if _, err := store.ListRevisions("bob", "Missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("listing the revisions of a missing sheet gave %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	pages "Pages"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+//ErrNotFound is returned when a requested user or sheet does not exist in the store
+var ErrNotFound = errors.New("could not find the requested document")
+
+//ErrExists is returned when a user or sheet can't be stored because one with the same name already exists
+var ErrExists = errors.New("a document with that name already exists")
+
+//ErrConflict is returned when a sheet kept being changed by other writes while we tried to change it
+var ErrConflict = errors.New("the sheet was changed by someone else, try again")
+
+//ErrInTrash is returned when a sheet can't be stored because one with the same name is in the trash
+var ErrInTrash = errors.New("a sheet with that name is in the trash, restore or purge it first")
+
+//Store is a storage backend for users and their character sheets
+type Store interface {
+	CheckUser(user string, pass string) (bool, error)                                            //Checks if the username and password match a stored user
+	CheckUserName(user string) (bool, error)                                                     //Checks if the username is already taken
+	GetSheets(user string) ([]pages.SheetInfo, error)                                            //Lists a user's character sheets
+	GetSheet(username string, sheetname string) (pages.Sheet, error)                             //Gets a given character sheet
+	GetSheetByID(username string, id string) (pages.Sheet, error)                                //Gets a character sheet by its ID
+	RegisterUser(user User) error                                                                //Registers a new user
+	RegisterSheet(user string, sheet pages.Sheet) error                                          //Registers a new character sheet with a user
+	UpdateSheet(user string, sheet pages.Sheet) error                                            //Replaces a user's stored sheet that has the same name
+	PatchSheet(user string, sheet string, ops []SheetOp) (pages.Sheet, error)                    //Applies a list of changes to a stored sheet together, refusing changes that break the sheet rules
+	ModifySheet(user string, sheet string, change func(*pages.Sheet) error) (pages.Sheet, error) //Changes a stored sheet with a function, without losing concurrent writes
+	RenameSheet(user string, sheet string, newName string) (pages.Sheet, error)                  //Gives a sheet a new name, keeping its ID and revisions
+	DeleteSheet(user string, sheet string) error                                                 //Moves a user's character sheet to the trash
+	ListTrash(user string) ([]pages.TrashedSheet, error)                                         //Lists the sheets in a user's trash, latest deleted first
+	RestoreSheet(user string, sheet string) error                                                //Takes a sheet out of the trash
+	PurgeSheet(user string, sheet string) error                                                  //Deletes a sheet in the trash for good, along with its revisions
+	PurgeTrash(before time.Time) (int, error)                                                    //Purges every user's sheets that went to the trash before the given time
+	ListRevisions(user string, sheet string) ([]pages.Revision, error)                           //Lists the saved revisions of a sheet, newest first and without their sheets
+	GetRevision(user string, sheet string, version int) (pages.Revision, error)                  //Gets one revision of a sheet along with the sheet as it was
+	RestoreRevision(user string, sheet string, version int) (pages.Sheet, error)                 //Puts an older revision of a sheet back in place, as a new revision
+	Close() error                                                                                //Releases the resources held by the store
+}
+
+//Makes a random ID for a new sheet
+func newID() string {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		panic(err) //The system's random source failing leaves us nothing to make IDs with
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	pages "Pages"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryTrashOrder(t *testing.T) {
+	store := testStore(t)
+	for _, name := range []string{"First", "Second", "Third"} {
+		registerSheet(t, store, pages.Sheet{Name: name, CharacterName: name + " Bo"})
+	}
+	store.DeleteSheet("bob", "Second")
+	time.Sleep(time.Millisecond)
+	store.DeleteSheet("bob", "First")
+	trash, err := store.ListTrash("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 2 || trash[0].Name != "First" || trash[1].Name != "Second" || trash[0].CharacterName != "First Bo" || trash[0].ID == "" {
+		t.Errorf("ListTrash gave %+v, want First then Second", trash)
+	}
+	if list, _ := store.GetSheets("bob"); len(list) != 1 || list[0].Name != "Third" {
+		t.Errorf("GetSheets gave %+v, want only Third", list)
+	}
+	if _, err := store.ListTrash("alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("listing the trash of a missing user gave %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryTrashRejected(t *testing.T) {
+	store := testStore(t)
+	registerSheet(t, store, pages.Sheet{Name: "Live"})
+	registerSheet(t, store, pages.Sheet{Name: "Trashed"})
+	store.DeleteSheet("bob", "Trashed")
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"deleting a trashed sheet", store.DeleteSheet("bob", "Trashed"), ErrNotFound},
+		{"restoring a live sheet", store.RestoreSheet("bob", "Live"), ErrNotFound},
+		{"purging a live sheet", store.PurgeSheet("bob", "Live"), ErrNotFound},
+		{"purging a missing sheet", store.PurgeSheet("bob", "Missing"), ErrNotFound},
+		{"changing a trashed sheet", modifyErr(store, "Trashed"), ErrNotFound},
+	}
+	for _, test := range tests {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("%s gave %v, want %v", test.name, test.err, test.want)
+		}
+	}
+	if _, err := store.RenameSheet("bob", "Live", "Trashed"); !errors.Is(err, ErrInTrash) {
+		t.Errorf("renaming to the name of a trashed sheet gave %v, want ErrInTrash", err)
+	}
+	if _, err := store.GetSheet("bob", "Live"); err != nil {
+		t.Errorf("a refused change lost the live sheet: %v", err)
+	}
+}
+
+//Gives the error of changing one of bob's sheets
+func modifyErr(store *MemoryStore, name string) error {
+	_, err := store.ModifySheet("bob", name, func(*pages.Sheet) error { return nil })
+	return err
+}
+
+func TestMemoryPurgeTrash(t *testing.T) {
+	store := testStore(t)
+	store.RegisterUser(User{Username: "alice"})
+	registerSheet(t, store, pages.Sheet{Name: "Old"})
+	registerSheet(t, store, pages.Sheet{Name: "Kept"})
+	if err := store.RegisterSheet("alice", pages.Sheet{Name: "Old"}); err != nil {
+		t.Fatal(err)
+	}
+	store.DeleteSheet("bob", "Old")
+	store.DeleteSheet("alice", "Old")
+	cutoff := time.Now().Add(time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	store.DeleteSheet("bob", "Kept")
+	purged, err := store.PurgeTrash(cutoff)
+	if err != nil || purged != 2 {
+		t.Errorf("PurgeTrash gave %d, %v, want 2", purged, err)
+	}
+	if trash, _ := store.ListTrash("bob"); len(trash) != 1 || trash[0].Name != "Kept" {
+		t.Errorf("bob's trash holds %+v, want Kept", trash)
+	}
+	if err := store.RegisterSheet("bob", pages.Sheet{Name: "Old"}); err != nil {
+		t.Errorf("the name of a purged sheet is still taken: %v", err)
+	}
+}
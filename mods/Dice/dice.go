@@ -0,0 +1,326 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Limits that keep a single roll from taking too long
+const (
+	MaxDice       = 100  //Most dice in one group
+	MaxSides      = 1000 //Most sides on a die
+	MaxTerms      = 20   //Most dice groups and numbers in one roll
+	MaxExplosions = 100  //Most extra dice exploding dice can add to one group
+)
+
+//ErrBadNotation is returned when a roll isn't written in dice notation we can read
+var ErrBadNotation = errors.New(`rolls must be written like 2d6+3, 4d6kh3, 1d20 adv, 3d6! or 2d6r1`)
+
+//ErrBadMode is returned for a mode we don't know, or advantage or disadvantage on a roll without a single d20 to roll twice
+var ErrBadMode = errors.New("the mode must be advantage or disadvantage, on a roll with a single d20 like 1d20+5")
+
+//Matches a group of dice and what comes after the sides, like "4d6kh3" or "d20"
+var groupPattern = regexp.MustCompile(`^(\d*)d(\d+|%)(.*)$`)
+
+//Matches one modifier of a dice group, like "kh3", "r1" or "!"
+var modifierPattern = regexp.MustCompile(`^(kh|kl|k|dh|dl|r|!)(\d*)`)
+
+//Die is a single die that was rolled
+type Die struct {
+	Value    int  `json:"value"`
+	Kept     bool `json:"kept"`     //Dice dropped by keep or drop modifiers don't count
+	Rerolled int  `json:"rerolled"` //The value the die showed before it was rerolled. 0 if it wasn't
+	Exploded bool `json:"exploded"` //The die was added because the one before it rolled its highest value
+}
+
+//Group is a group of dice in a roll, like the 4d6kh3 in 4d6kh3+2
+type Group struct {
+	Notation string `json:"notation"`
+	Sign     int    `json:"sign"` //-1 if the group is subtracted
+	Dice     []Die  `json:"dice"`
+	Total    int    `json:"total"` //The sum of the kept dice, without the sign
+}
+
+//Result is a finished roll
+type Result struct {
+	Notation string  `json:"notation"`
+	Mode     string  `json:"mode"` //"advantage", "disadvantage" or empty
+	Groups   []Group `json:"groups"`
+	Modifier int     `json:"modifier"` //The sum of the plain numbers in the roll
+	Total    int     `json:"total"`
+	Text     string  `json:"text"` //The roll written out, like "2d6+3: [4, 2] + 3 = 9"
+}
+
+//Roller rolls dice with its own random source, so rolls can be repeated by giving it the same seed
+type Roller struct {
+	mutex sync.Mutex
+	rng   *rand.Rand
+}
+
+//Term of a roll before it's rolled
+type term struct {
+	sign     int
+	count    int
+	sides    int //0 for a plain number, which is kept in count
+	keep     int //Amount of dice to keep. 0 keeps all of them
+	keepLow  bool
+	reroll   int //Dice showing this or lower are rerolled once. 0 to not reroll
+	explode  bool
+	notation string
+}
+
+//NewRoller makes a roller seeded with the given seed
+func NewRoller(seed int64) *Roller {
+	return &Roller{rng: rand.New(rand.NewSource(seed))}
+}
+
+//Die rolls a single die with the given amount of sides. Gives 0 for a die without sides
+func (r *Roller) Die(sides int) int {
+	if sides < 1 {
+		return 0
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.rng.Intn(sides) + 1
+}
+
+//Roll rolls dice written in standard notation, like "2d6+3", "4d6kh3", "1d20 adv", "3d6!" or "2d6r1".
+//Mode can be "advantage" or "disadvantage" to roll the d20 of the roll twice, and can also be written at the end of the notation
+func (r *Roller) Roll(notation string, mode string) (Result, error) {
+	notation, mode, ok := splitMode(notation, mode)
+	if !ok {
+		return Result{}, ErrBadMode
+	}
+	terms, err := parse(notation)
+	if err != nil {
+		return Result{}, err
+	}
+	if mode != "" {
+		if err := applyMode(terms, mode); err != nil {
+			return Result{}, err
+		}
+	}
+	result := Result{Notation: notation, Mode: mode, Groups: []Group{}}
+	parts := []string{}
+	for _, t := range terms {
+		if t.sides == 0 {
+			result.Modifier += t.sign * t.count
+			parts = append(parts, signed(t.sign, strconv.Itoa(t.count), len(parts)))
+			continue
+		}
+		group := r.rollGroup(t)
+		result.Groups = append(result.Groups, group)
+		result.Total += group.Sign * group.Total
+		parts = append(parts, signed(t.sign, diceText(group.Dice), len(parts)))
+	}
+	result.Total += result.Modifier
+	result.Text = fmt.Sprintf("%s: %s = %d", notation, strings.Join(parts, " "), result.Total)
+	if mode != "" {
+		result.Text = fmt.Sprintf("%s (%s)", result.Text, mode)
+	}
+	return result, nil
+}
+
+//Rolls the dice of a group, with its rerolls, explosions and kept dice
+func (r *Roller) rollGroup(t term) Group {
+	group := Group{Notation: t.notation, Sign: t.sign, Dice: []Die{}}
+	explosions := 0
+	rolled := t.count //Dice rolled past this were added by explosions
+	for i := 0; i < t.count; i++ {
+		die := Die{Value: r.Die(t.sides), Kept: true, Exploded: i >= rolled}
+		if t.reroll > 0 && die.Value <= t.reroll {
+			die.Rerolled = die.Value
+			die.Value = r.Die(t.sides)
+		}
+		group.Dice = append(group.Dice, die)
+		if t.explode && die.Value == t.sides && explosions < MaxExplosions {
+			explosions++
+			t.count++
+		}
+	}
+	if t.keep > 0 && t.keep < len(group.Dice) {
+		keepDice(group.Dice, t.keep, t.keepLow)
+	}
+	for _, die := range group.Dice {
+		if die.Kept {
+			group.Total += die.Value
+		}
+	}
+	return group
+}
+
+//Marks all but the highest, or lowest, amount of dice as dropped
+func keepDice(dice []Die, keep int, low bool) {
+	for dropped := 0; dropped < len(dice)-keep; dropped++ {
+		worst := -1 //The kept die that is dropped next
+		for i, die := range dice {
+			if !die.Kept {
+				continue
+			}
+			if worst == -1 || (!low && die.Value < dice[worst].Value) || (low && die.Value > dice[worst].Value) {
+				worst = i
+			}
+		}
+		dice[worst].Kept = false
+	}
+}
+
+//Takes a mode written at the end of the notation, like "1d20+5 adv", off the notation. Gives false for a mode we don't know
+func splitMode(notation string, mode string) (string, string, bool) {
+	notation = strings.ToLower(strings.TrimSpace(notation))
+	modes := map[string]string{"adv": "advantage", "advantage": "advantage", "dis": "disadvantage", "disadvantage": "disadvantage"}
+	fields := strings.Fields(notation)
+	if len(fields) > 1 && modes[fields[len(fields)-1]] != "" {
+		mode = modes[fields[len(fields)-1]]
+		fields = fields[:len(fields)-1]
+	}
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != "" && modes[mode] == "" {
+		return notation, mode, false
+	}
+	return strings.Join(fields, ""), modes[mode], true
+}
+
+//Turns the single d20 of a roll into two, keeping the higher one for advantage and the lower one for disadvantage
+func applyMode(terms []term, mode string) error {
+	for i := range terms {
+		if terms[i].sides == 20 && terms[i].count == 1 && terms[i].keep == 0 {
+			terms[i].count = 2
+			terms[i].keep = 1
+			terms[i].keepLow = mode == "disadvantage"
+			return nil
+		}
+	}
+	return ErrBadMode
+}
+
+//Reads a roll written in dice notation into its terms
+func parse(notation string) ([]term, error) {
+	if notation == "" {
+		return nil, ErrBadNotation
+	}
+	terms := []term{}
+	sign := 1
+	start := 0
+	if notation[0] == '-' || notation[0] == '+' {
+		sign = map[byte]int{'-': -1, '+': 1}[notation[0]]
+		start = 1
+	}
+	for i := start; i <= len(notation); i++ {
+		if i < len(notation) && notation[i] != '+' && notation[i] != '-' {
+			continue
+		}
+		t, err := parseTerm(notation[start:i])
+		if err != nil {
+			return nil, err
+		}
+		t.sign = sign
+		terms = append(terms, t)
+		if len(terms) > MaxTerms {
+			return nil, ErrBadNotation
+		}
+		if i < len(notation) && notation[i] == '-' {
+			sign = -1
+		} else {
+			sign = 1
+		}
+		start = i + 1
+	}
+	return terms, nil
+}
+
+//Reads a single term of a roll, which is a number or a group of dice with its modifiers
+func parseTerm(text string) (term, error) {
+	if number, err := strconv.Atoi(text); err == nil && number >= 0 {
+		return term{count: number, notation: text}, nil
+	}
+	match := groupPattern.FindStringSubmatch(text)
+	if match == nil {
+		return term{}, ErrBadNotation
+	}
+	t := term{count: 1, notation: text}
+	var err error
+	if match[1] != "" {
+		if t.count, err = strconv.Atoi(match[1]); err != nil { //Too big to read
+			return term{}, ErrBadNotation
+		}
+	}
+	if match[2] == "%" {
+		t.sides = 100
+	} else if t.sides, err = strconv.Atoi(match[2]); err != nil {
+		return term{}, ErrBadNotation
+	}
+	if t.count < 1 || t.count > MaxDice || t.sides < 1 || t.sides > MaxSides {
+		return term{}, ErrBadNotation
+	}
+	rest := match[3]
+	for rest != "" {
+		modifier := modifierPattern.FindStringSubmatch(rest)
+		if modifier == nil {
+			return term{}, ErrBadNotation
+		}
+		rest = rest[len(modifier[0]):]
+		amount := 1
+		if modifier[2] != "" {
+			if amount, err = strconv.Atoi(modifier[2]); err != nil {
+				return term{}, ErrBadNotation
+			}
+		}
+		switch modifier[1] {
+		case "kh", "k", "kl", "dl", "dh":
+			t.keep, t.keepLow = amount, modifier[1] == "kl"
+			if modifier[1] == "dl" || modifier[1] == "dh" {
+				t.keep, t.keepLow = t.count-amount, modifier[1] == "dh"
+			}
+			if t.keep < 1 { //Keeping no dice would read as keeping all of them
+				return term{}, ErrBadNotation
+			}
+		case "r":
+			t.reroll = amount
+		case "!":
+			if modifier[2] != "" {
+				return term{}, ErrBadNotation
+			}
+			t.explode = true
+		}
+		if t.keep > t.count || t.reroll >= t.sides || (t.explode && t.sides == 1) {
+			return term{}, ErrBadNotation
+		}
+	}
+	return t, nil
+}
+
+//Writes the dice of a group like "[6, 3, ~1~]", with the dropped dice struck through
+func diceText(dice []Die) string {
+	values := []string{}
+	for _, die := range dice {
+		value := strconv.Itoa(die.Value)
+		if die.Rerolled > 0 {
+			value = fmt.Sprintf("%d>%d", die.Rerolled, die.Value)
+		}
+		if die.Exploded {
+			value += "!"
+		}
+		if !die.Kept {
+			value = "~" + value + "~"
+		}
+		values = append(values, value)
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+//Writes a term with its sign in front, leaving out the plus of the first term
+func signed(sign int, text string, index int) string {
+	if sign < 0 {
+		return "- " + text
+	}
+	if index == 0 {
+		return text
+	}
+	return "+ " + text
+}
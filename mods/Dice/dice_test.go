@@ -0,0 +1,159 @@
+package dice
+
+import (
+	"reflect"
+	"testing"
+)
+
+//Counts the kept dice of a group and sums them up
+func kept(group Group) (int, int) {
+	count, total := 0, 0
+	for _, die := range group.Dice {
+		if die.Kept {
+			count++
+			total += die.Value
+		}
+	}
+	return count, total
+}
+
+func TestSeededRollsRepeat(t *testing.T) {
+	first, second := NewRoller(42), NewRoller(42)
+	for _, notation := range []string{"2d6+3", "4d6kh3", "1d20 adv", "3d6!", "2d6r1"} {
+		a, errA := first.Roll(notation, "")
+		b, errB := second.Roll(notation, "")
+		if errA != nil || errB != nil {
+			t.Fatalf("rolling %q gave %v, %v", notation, errA, errB)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("two rollers with the same seed rolled %q differently: %s and %s", notation, a.Text, b.Text)
+		}
+	}
+}
+
+func TestDieRange(t *testing.T) {
+	roller := NewRoller(1)
+	for i := 0; i < 1000; i++ {
+		if value := roller.Die(6); value < 1 || value > 6 {
+			t.Fatalf("a d6 rolled %d", value)
+		}
+	}
+	if value := roller.Die(0); value != 0 {
+		t.Errorf("a die without sides rolled %d", value)
+	}
+}
+
+func TestKeepAndDrop(t *testing.T) {
+	tests := []struct {
+		notation string
+		kept     int
+		highest  bool //The kept dice are the highest ones
+	}{
+		{"4d6kh3", 3, true},
+		{"4d6k3", 3, true},
+		{"4d6kl1", 1, false},
+		{"4d6dl1", 3, true},
+		{"4d6dh3", 1, false},
+		{"4d6", 4, true},
+	}
+	roller := NewRoller(7)
+	for _, test := range tests {
+		for i := 0; i < 50; i++ {
+			result, err := roller.Roll(test.notation, "")
+			if err != nil {
+				t.Fatalf("rolling %q gave %v", test.notation, err)
+			}
+			group := result.Groups[0]
+			count, total := kept(group)
+			if count != test.kept || total != group.Total || total != result.Total {
+				t.Fatalf("%q kept %d dice adding up to %d, with a total of %d: %s", test.notation, count, total, result.Total, result.Text)
+			}
+			for _, a := range group.Dice {
+				for _, b := range group.Dice {
+					if a.Kept && !b.Kept && ((test.highest && a.Value < b.Value) || (!test.highest && a.Value > b.Value)) {
+						t.Fatalf("%q kept the wrong dice: %s", test.notation, result.Text)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestModifiersAndSigns(t *testing.T) {
+	result, err := NewRoller(3).Roll("1d4-1d4+10-2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Modifier != 8 || len(result.Groups) != 2 || result.Groups[1].Sign != -1 {
+		t.Fatalf("1d4-1d4+10-2 gave %+v", result)
+	}
+	if result.Total != result.Groups[0].Total-result.Groups[1].Total+8 {
+		t.Errorf("the total %d doesn't add up: %s", result.Total, result.Text)
+	}
+}
+
+func TestAdvantage(t *testing.T) {
+	roller := NewRoller(5)
+	for _, mode := range []string{"advantage", "disadvantage"} {
+		result, err := roller.Roll("1d20+5", mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dice := result.Groups[0].Dice
+		if len(dice) != 2 {
+			t.Fatalf("%s rolled %d dice", mode, len(dice))
+		}
+		high, low := dice[0].Value, dice[1].Value
+		if high < low {
+			high, low = low, high
+		}
+		want := high
+		if mode == "disadvantage" {
+			want = low
+		}
+		if result.Total != want+5 {
+			t.Errorf("%s gave %d from %s, want %d", mode, result.Total, result.Text, want+5)
+		}
+	}
+	if _, err := roller.Roll("2d6", "advantage"); err != ErrBadMode {
+		t.Errorf("advantage without a d20 gave %v, want ErrBadMode", err)
+	}
+	if _, err := roller.Roll("1d20", "luck"); err != ErrBadMode {
+		t.Errorf("an unknown mode gave %v, want ErrBadMode", err)
+	}
+}
+
+func TestRerollAndExplode(t *testing.T) {
+	roller := NewRoller(11)
+	for i := 0; i < 200; i++ {
+		result, _ := roller.Roll("2d6r2", "")
+		for _, die := range result.Groups[0].Dice {
+			if die.Rerolled > 2 || (die.Rerolled == 0 && die.Value <= 2) {
+				t.Fatalf("2d6r2 didn't reroll right: %s", result.Text)
+			}
+		}
+		exploding, _ := roller.Roll("2d6!", "")
+		dice := exploding.Groups[0].Dice
+		sixes := 0
+		for _, die := range dice {
+			if die.Value == 6 {
+				sixes++
+			}
+		}
+		if len(dice) != 2+sixes {
+			t.Fatalf("2d6! added %d dice for %d sixes: %s", len(dice)-2, sixes, exploding.Text)
+		}
+	}
+}
+
+func TestBadNotation(t *testing.T) {
+	for _, notation := range []string{
+		"", "d", "2d", "0d6", "2d0", "101d6", "1d1001", "abc", "2d6+", "2d6x",
+		"4d6kh0", "4d6kl0", "4d6dl4", "4d6dh5", "4d6kh5", "2d6r6", "1d1!", "2d6!2",
+		"99999999999999999999d6", "1d99999999999999999999", "4d6r99999999999999999999", "4d6kh99999999999999999999",
+	} {
+		if _, err := NewRoller(1).Roll(notation, ""); err != ErrBadNotation {
+			t.Errorf("rolling %q gave %v, want ErrBadNotation", notation, err)
+		}
+	}
+}
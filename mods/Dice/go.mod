@@ -0,0 +1,3 @@
+module Dice
+
+go 1.15
@@ -0,0 +1,44 @@
+package pages
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ClassLevel holds the levels a character has in one of its classes
+type ClassLevel struct {
+	Name         string `json:"name"`
+	Subclass     string `json:"subclass"`
+	Level        int    `json:"level"`
+	HitDie       string `json:"hitDie"`       //The hit die of the class, like "1d10"
+	HitDiceSpent int    `json:"hitDiceSpent"` //Hit dice of the class spent since the last long rest
+}
+
+//MigrateClasses fills the class list of sheets saved before multiclassing was supported, using their single class,
+//level and hit die. Then it updates the summary fields to match the class list
+func (s *Sheet) MigrateClasses() {
+	if len(s.Classes) == 0 && strings.TrimSpace(s.Class) != "" {
+		s.Classes = []ClassLevel{{Name: s.Class, Level: s.Level, HitDie: s.HitDie.Name}}
+	}
+	s.SyncClasses()
+}
+
+//SyncClasses updates Class, Level and HitDie, which sum up the class list for single-class readers.
+//Level becomes the total character level, and Class reads like "Fighter 2 / Wizard 5"
+func (s *Sheet) SyncClasses() {
+	if len(s.Classes) == 0 {
+		return
+	}
+	names := []string{}
+	s.Level = 0
+	for _, class := range s.Classes {
+		names = append(names, fmt.Sprintf("%s %d", class.Name, class.Level))
+		s.Level += class.Level
+	}
+	if len(s.Classes) == 1 { //A single class keeps its plain name, like sheets always had
+		s.Class = s.Classes[0].Name
+	} else {
+		s.Class = strings.Join(names, " / ")
+	}
+	s.HitDie = HitDice{Name: s.Classes[0].HitDie, Amount: s.Level}
+}
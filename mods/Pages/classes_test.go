@@ -0,0 +1,27 @@
+package pages
+
+import "testing"
+
+func TestMigrateClasses(t *testing.T) {
+	old := Sheet{Class: "Rogue", Level: 4, HitDie: HitDice{Name: "1d8", Amount: 4}}
+	old.MigrateClasses()
+	if len(old.Classes) != 1 || old.Classes[0] != (ClassLevel{Name: "Rogue", Level: 4, HitDie: "1d8"}) {
+		t.Errorf("the class list is %+v", old.Classes)
+	}
+	if old.Class != "Rogue" || old.Level != 4 {
+		t.Errorf("the summary is %q level %d", old.Class, old.Level)
+	}
+	empty := Sheet{}
+	empty.MigrateClasses()
+	if empty.Classes != nil || empty.Class != "" {
+		t.Errorf("a sheet without a class got %+v", empty.Classes)
+	}
+}
+
+func TestSyncClasses(t *testing.T) {
+	sheet := Sheet{Class: "Fighter", Level: 2, Classes: []ClassLevel{{Name: "Fighter", Level: 2, HitDie: "1d10"}, {Name: "Wizard", Level: 5, HitDie: "1d6"}}}
+	sheet.SyncClasses()
+	if sheet.Class != "Fighter 2 / Wizard 5" || sheet.Level != 7 || sheet.HitDie != (HitDice{Name: "1d10", Amount: 7}) {
+		t.Errorf("the summary is %q level %d with hit dice %+v", sheet.Class, sheet.Level, sheet.HitDie)
+	}
+}
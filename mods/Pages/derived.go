@@ -0,0 +1,58 @@
+package pages
+
+//Bonus is the computed bonus of a saving throw or skill
+type Bonus struct {
+	Name       string `json:"name"`
+	Ability    string `json:"ability"`
+	Bonus      int    `json:"bonus"`
+	Proficient bool   `json:"proficient"`
+	Expert     bool   `json:"expert"`
+}
+
+//Mismatch is a value stored on a sheet that disagrees with the value computed from the rest of the sheet
+type Mismatch struct {
+	Field    string `json:"field"`
+	Stored   int    `json:"stored"`
+	Computed int    `json:"computed"`
+}
+
+//AttackBonus is the computed to-hit bonus and damage of an attack
+type AttackBonus struct {
+	Name       string `json:"name"`
+	Ability    string `json:"ability"` //The ability score the attack ended up using. Empty for a spell attack of a character that doesn't cast spells
+	ToHit      int    `json:"toHit"`
+	Damage     string `json:"damage"` //The damage dice with the modifier, like "1d8+3"
+	DamageType string `json:"damageType"`
+	Range      string `json:"range"`
+}
+
+//Derived holds the values that are computed from a sheet instead of typed in
+type Derived struct {
+	Modifiers           map[string]int `json:"modifiers"` //Ability modifiers keyed by ability name
+	ProficiencyBonus    int            `json:"proficiencyBonus"`
+	Saves               []Bonus        `json:"saves"`
+	Skills              []Bonus        `json:"skills"`
+	Initiative          int            `json:"initiative"`
+	PassivePerception   int            `json:"passivePerception"`
+	SpellcastingAbility string         `json:"spellcastingAbility"` //Empty if the character doesn't cast spells
+	SpellSaveDC         int            `json:"spellSaveDC"`
+	SpellAttack         int            `json:"spellAttack"`
+	SpellSlots          []SpellSlot    `json:"spellSlots"` //The slots the class levels give, without the used ones
+	PactSlots           SpellSlot      `json:"pactSlots"`
+	AC                  int            `json:"ac"`           //The AC the character has, which is the override if the sheet has one
+	ComputedAC          int            `json:"computedAC"`   //The AC from the equipped armor, shield and items
+	ACSources           []string       `json:"acSources"`    //What the computed AC is made of, like "Chain mail 16" and "Shield +2"
+	ACOverridden        bool           `json:"acOverridden"` //The sheet overrides the computed AC
+	Attacks             []AttackBonus  `json:"attacks"`
+	CarriedWeight       float64        `json:"carriedWeight"`     //Pounds carried, coins included
+	CarryingCapacity    float64        `json:"carryingCapacity"`  //Pounds the character can carry
+	Encumbrance         string         `json:"encumbrance"`       //How the carried weight slows the character. Empty if it doesn't
+	Attuned             int            `json:"attuned"`           //Amount of attuned items
+	WealthGP            float64        `json:"wealthGP"`          //The worth of all the coins in gold pieces
+	InventoryWarnings   []string       `json:"inventoryWarnings"` //Like being over the carrying capacity or attuned to too many items
+	Speed               int            `json:"speed"`             //Speed after exhaustion and conditions
+	MaxHitPoints        int            `json:"maxHitPoints"`      //Max hit points after exhaustion
+	Effects             []string       `json:"effects"`           //What the active conditions and exhaustion do, like disadvantage on checks
+	Dead                bool           `json:"dead"`
+	Mismatches          []Mismatch     `json:"mismatches"`
+}
@@ -0,0 +1,104 @@
+package pages
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//UntrackedFields are the top level sheet fields that changes aren't recorded for in the revision history
+var UntrackedFields = []string{"version", "rolls"}
+
+//Change is a single field that differs between two versions of a sheet
+type Change struct {
+	Path string `json:"path"` //Json path of the field, like "scores.strength" or "inventory.2.name"
+	Old  string `json:"old"`  //Json value of the field in the older sheet. Empty if the field wasn't there
+	New  string `json:"new"`  //Json value of the field in the newer sheet. Empty if the field is gone
+}
+
+//Diff lists the fields that differ between two sheets, ordered by path. Empty lists count the same as missing ones
+func Diff(old Sheet, new Sheet) []Change {
+	oldFields, newFields := flatten(old), flatten(new)
+	paths := []string{}
+	for path := range oldFields {
+		paths = append(paths, path)
+	}
+	for path := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	changes := []Change{}
+	for _, path := range paths {
+		if oldFields[path] != newFields[path] && !untracked(path) {
+			changes = append(changes, Change{Path: path, Old: oldFields[path], New: newFields[path]})
+		}
+	}
+	return changes
+}
+
+//DescribeChanges sums up a list of changes by the top level fields they touch, like "Changed hitPoints and inventory"
+func DescribeChanges(changes []Change) string {
+	fields := []string{}
+	for _, change := range changes {
+		field := strings.SplitN(change.Path, ".", 2)[0]
+		if len(fields) == 0 || fields[len(fields)-1] != field { //Changes are ordered by path, so the same fields are next to each other
+			fields = append(fields, field)
+		}
+	}
+	switch {
+	case len(fields) == 0:
+		return "No changes"
+	case len(fields) == 1:
+		return "Changed " + fields[0]
+	case len(fields) > 4:
+		return "Changed " + strings.Join(fields[:3], ", ") + " and " + strconv.Itoa(len(fields)-3) + " more fields"
+	default:
+		return "Changed " + strings.Join(fields[:len(fields)-1], ", ") + " and " + fields[len(fields)-1]
+	}
+}
+
+//Checks if a path is inside one of the untracked fields
+func untracked(path string) bool {
+	field := strings.SplitN(path, ".", 2)[0]
+	for _, name := range UntrackedFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+//Turns a sheet into a map from the json path of every value to its json text
+func flatten(sheet Sheet) map[string]string {
+	fields := map[string]string{}
+	var value interface{}
+	data, _ := json.Marshal(sheet)
+	json.Unmarshal(data, &value)
+	flattenValue("", value, fields)
+	return fields
+}
+
+//Adds a json value and everything inside it to the flattened fields
+func flattenValue(path string, value interface{}, fields map[string]string) {
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			flattenValue(prefix+key, inner, fields)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			flattenValue(prefix+strconv.Itoa(i), inner, fields)
+		}
+	case nil: //Missing lists and empty ones look the same
+	default:
+		data, _ := json.Marshal(v)
+		fields[path] = string(data)
+	}
+}
@@ -0,0 +1,46 @@
+package pages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := Sheet{Name: "Test", Scores: Abilities{Strength: 10}, Inventory: []Item{{Name: "Rope", Amount: 1}}, Version: 3}
+	new := Sheet{Name: "Test", Scores: Abilities{Strength: 12}, Inventory: []Item{{Name: "Torch", Amount: 1}},
+		Version: 4, Rolls: []RollRecord{{Notation: "1d20"}}}
+	want := []Change{
+		{Path: "inventory.0.name", Old: `"Rope"`, New: `"Torch"`},
+		{Path: "scores.strength", Old: "10", New: "12"},
+	}
+	if changes := Diff(old, new); !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff gave %+v, want %+v", changes, want)
+	}
+	if changes := Diff(Sheet{Languages: []string{}}, Sheet{}); len(changes) != 0 {
+		t.Errorf("an empty list and a missing one differ by %+v", changes)
+	}
+	if changes := Diff(Sheet{}, Sheet{Languages: []string{"Elvish"}}); len(changes) != 1 || changes[0] != (Change{Path: "languages.0", New: `"Elvish"`}) {
+		t.Errorf("adding a language gave %+v", changes)
+	}
+}
+
+func TestDescribeChanges(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{nil, "No changes"},
+		{[]string{"inventory.0.amount", "inventory.1.name"}, "Changed inventory"},
+		{[]string{"hitPoints.current", "inventory.0.amount", "race"}, "Changed hitPoints, inventory and race"},
+		{[]string{"allies.0.name", "bonds", "flaw", "ideals", "race"}, "Changed allies, bonds, flaw and 2 more fields"},
+	}
+	for _, test := range tests {
+		changes := []Change{}
+		for _, path := range test.paths {
+			changes = append(changes, Change{Path: path})
+		}
+		if description := DescribeChanges(changes); description != test.want {
+			t.Errorf("DescribeChanges of %v gave %q, want %q", test.paths, description, test.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package pages
+
+//HitPoints holds the hit points of a character, as they change during play
+type HitPoints struct {
+	Max        int        `json:"max"`
+	Current    int        `json:"current"`
+	Temp       int        `json:"temp"` //Temporary hit points, which are lost before the current ones
+	DeathSaves DeathSaves `json:"deathSaves"`
+	Stable     bool       `json:"stable"` //At 0 hit points, but no longer making death saves
+	Dead       bool       `json:"dead"`
+}
+
+//DeathSaves counts the death saving throws made since the character dropped to 0 hit points
+type DeathSaves struct {
+	Successes int `json:"successes"`
+	Failures  int `json:"failures"`
+}
+
+//MigrateHitPoints fills the hit points of sheets saved before they were tracked, starting them at full health.
+//Then it sets Health to the max hit points, which it sums up for older readers
+func (s *Sheet) MigrateHitPoints() {
+	if s.HitPoints.Max == 0 && s.Health > 0 {
+		s.HitPoints.Max = s.Health
+		s.HitPoints.Current = s.Health
+	}
+	s.Health = s.HitPoints.Max
+}
+
+//Migrate brings a sheet saved by an older version of the app up to the current layout
+func (s *Sheet) Migrate() {
+	s.MigrateClasses()
+	s.MigrateHitPoints()
+}
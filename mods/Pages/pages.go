@@ -1,5 +1,7 @@
 package pages
 
+import "time"
+
 //Abilities represents a character's ability scores
 type Abilities struct {
 	Strength     int `json:"strength"`
@@ -19,11 +21,39 @@ type Coin struct {
 	PP int `json:"pp"`
 }
 
+//Transaction is a change to the coins of a character, recorded in its ledger
+type Transaction struct {
+	Date        time.Time `json:"date"`
+	Action      string    `json:"action"` //"spend", "receive" or "consolidate"
+	Amount      Coin      `json:"amount"` //The coins spent or received, as they were asked for
+	Description string    `json:"description"`
+	Balance     Coin      `json:"balance"` //The coins the character had afterwards
+}
+
+//RollRecord is a dice roll made for a character, kept in its roll log
+type RollRecord struct {
+	Date     time.Time `json:"date"`
+	Label    string    `json:"label"` //What was rolled for, like "Stealth check" or "Longsword damage"
+	Notation string    `json:"notation"`
+	Mode     string    `json:"mode"` //"advantage", "disadvantage" or empty
+	Total    int       `json:"total"`
+	Text     string    `json:"text"` //The roll written out with every die, like "2d6+3: [4, 2] + 3 = 9"
+}
+
 //Item represents an item in a character's inventory
 type Item struct {
-	Name        string `json:"name"`
-	Amount      int    `json:"amount"`
-	Description string `json:"description"`
+	Name        string  `json:"name"`
+	Amount      int     `json:"amount"`
+	Description string  `json:"description"`
+	Weight      float64 `json:"weight"`     //Weight of one of the item in pounds
+	Value       int     `json:"value"`      //Value of one of the item in copper pieces
+	Equipped    bool    `json:"equipped"`   //Only equipped items count towards the AC
+	Attuned     bool    `json:"attuned"`    //A character can be attuned to at most three magic items
+	Container   string  `json:"container"`  //Name of the item this item is kept in, like "Backpack". Empty if it's carried on its own
+	Weightless  bool    `json:"weightless"` //The items kept in this item don't count towards the carried weight, like in a bag of holding
+	Armor       string  `json:"armor"`      //The kind of armor the item is, one of ArmorTypes. Empty if it isn't armor
+	ArmorClass  int     `json:"armorClass"` //The base AC of armor, or the AC a shield adds
+	ACBonus     int     `json:"acBonus"`    //A flat bonus to AC, like the 1 of +1 armor or a ring of protection
 }
 
 //Feat represents a feat a character might have
@@ -46,72 +76,228 @@ type HitDice struct {
 
 //Spell represents a spell a character might have
 type Spell struct {
-	Name        string `json:"name"`
-	Level       int    `json:"level"`
-	Description string `json:"description"`
+	Name          string `json:"name"`
+	Level         int    `json:"level"` //0 for cantrips
+	Description   string `json:"description"`
+	Prepared      bool   `json:"prepared"` //If the spell is prepared today. Cantrips and the spells of known casters are always ready
+	Ritual        bool   `json:"ritual"`
+	Concentration bool   `json:"concentration"`
+}
+
+//SpellSlot holds the slots a character has of one spell level
+type SpellSlot struct {
+	Level int `json:"level"`
+	Max   int `json:"max"`
+	Used  int `json:"used"`
+}
+
+//LevelRecord records a single level-up of a character
+type LevelRecord struct {
+	Class      string    `json:"class"`      //The class the level was gained in
+	Level      int       `json:"level"`      //The total character level that was reached
+	Date       time.Time `json:"date"`       //When the level-up happened
+	Experience int       `json:"experience"` //Exp the character had when leveling up
+	HPGained   int       `json:"hpGained"`   //Hit points gained, including the constitution modifier
+	HPMethod   string    `json:"hpMethod"`   //How the hit points were decided. "average", "roll" or "manual"
+	Milestone  bool      `json:"milestone"`  //If the level was given by milestone instead of exp
+}
+
+//Resource is a class feature or other ability with limited uses, like Rage or Ki points
+type Resource struct {
+	Name     string `json:"name"`
+	Current  int    `json:"current"`
+	Max      int    `json:"max"`
+	Recharge string `json:"recharge"` //When the uses come back. One of Recharges
+	Formula  string `json:"formula"`  //Computes the max uses from the sheet, like "monk" or "1+cha". Empty to keep Max as typed in
+}
+
+//Attack is a weapon or spell the character attacks with
+type Attack struct {
+	Name       string `json:"name"`
+	Ability    string `json:"ability"` //The ability the attack uses. One of AttackAbilities
+	Proficient bool   `json:"proficient"`
+	Damage     string `json:"damage"`     //The damage dice, like "1d8" or "2d6"
+	DamageType string `json:"damageType"` //One of DamageTypes
+	Range      string `json:"range"`      //Free text, like "5ft" or "80/320ft"
+	MagicBonus int    `json:"magicBonus"` //Added to both to-hit and damage, like the 1 of a +1 longsword
+}
+
+//Condition is a condition affecting the character, like poisoned or prone
+type Condition struct {
+	Name     string `json:"name"` //One of ConditionNames
+	Source   string `json:"source"`
+	Duration string `json:"duration"` //Free text, like "1 minute" or "until the end of its next turn"
+}
+
+//Event is something that happened to a character, like a rest
+type Event struct {
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"` //What kind of event it is, like "shortRest" or "longRest"
+	Description string    `json:"description"`
 }
 
 //Sheet holds all the character sheet data
 type Sheet struct {
-	Owner             string    `json:"owner"`
-	Name              string    `json:"name"`
-	CharacterName     string    `json:"characterName"`
-	Age               int       `json:"age"`
-	Weight            string    `json:"weight"`
-	Height            string    `json:"height"`
-	Size              string    `json:"size"`
-	Gender            string    `json:"gender"`
-	EyeColor          string    `json:"eyeColor"`
-	Skin              string    `json:"skin"`
-	Class             string    `json:"class"`
-	Race              string    `json:"race"`
-	Level             int       `json:"level"`
-	Allignment        string    `json:"allignment"`
-	Background        string    `json:"background"`
-	CurrentExpirience int       `json:"currentExpirience"`
-	NextExpirience    int       `json:"nextExpirience"`
-	Proficiency       int       `json:"proficiency"`
-	Scores            Abilities `json:"scores"`
-	Saves             []string  `json:"saves"`
-	ProficientSkills  []string  `json:"proficientSkills"`
-	ExpertSkills      []string  `json:"expertSkills"`
-	Languages         []string  `json:"languages"`
-	Tools             []string  `json:"tools"`
-	Vehicles          []string  `json:"vehicles"`
-	Weapons           []string  `json:"weapons"`
-	Armor             []string  `json:"armor"`
-	Inventory         []Item    `json:"inventory"`
-	AC                int       `json:"ac"`
-	Initiative        int       `json:"initiative"`
-	Speed             int       `json:"speed"`
-	Ideals            string    `json:"ideals"`
-	Bonds             string    `json:"bonds"`
-	Flaw              string    `json:"flaw"`
-	Feats             []Feat    `json:"feats"`
-	Money             Coin      `json:"money"`
-	PassivePerception int       `json:"passivePerception"`
-	Backstory         string    `json:"backstory"`
-	Allies            []Ally    `json:"allies"`
-	HitDie            HitDice   `json:"hitDie"`
-	Health            int       `json:"health"`
-	Spells            []Spell   `json:"spells"`
+	ID                  string        `json:"id"` //Stays the same for the life of the sheet, even when it is renamed
+	Owner               string        `json:"owner"`
+	Name                string        `json:"name"`
+	CharacterName       string        `json:"characterName"`
+	Age                 int           `json:"age"`
+	Weight              string        `json:"weight"`
+	Height              string        `json:"height"`
+	Size                string        `json:"size"`
+	Gender              string        `json:"gender"`
+	EyeColor            string        `json:"eyeColor"`
+	Skin                string        `json:"skin"`
+	Class               string        `json:"class"` //Sums up Classes. Set by SyncClasses
+	Race                string        `json:"race"`
+	Level               int           `json:"level"` //The total character level. Set by SyncClasses
+	Allignment          string        `json:"allignment"`
+	Background          string        `json:"background"`
+	CurrentExpirience   int           `json:"currentExpirience"`
+	NextExpirience      int           `json:"nextExpirience"`
+	Proficiency         int           `json:"proficiency"`
+	Scores              Abilities     `json:"scores"`
+	Saves               []string      `json:"saves"`
+	ProficientSkills    []string      `json:"proficientSkills"`
+	ExpertSkills        []string      `json:"expertSkills"`
+	Languages           []string      `json:"languages"`
+	Tools               []string      `json:"tools"`
+	Vehicles            []string      `json:"vehicles"`
+	Weapons             []string      `json:"weapons"`
+	Armor               []string      `json:"armor"`
+	Inventory           []Item        `json:"inventory"`
+	Encumbrance         string        `json:"encumbrance"` //The encumbrance rule the sheet uses, one of EncumbranceRules. Empty uses "standard"
+	AC                  int           `json:"ac"`          //Overrides the AC computed from the equipped armor. 0 to use the computed AC
+	Initiative          int           `json:"initiative"`
+	Speed               int           `json:"speed"`
+	Ideals              string        `json:"ideals"`
+	Bonds               string        `json:"bonds"`
+	Flaw                string        `json:"flaw"`
+	Feats               []Feat        `json:"feats"`
+	Money               Coin          `json:"money"`
+	PassivePerception   int           `json:"passivePerception"`
+	Backstory           string        `json:"backstory"`
+	Allies              []Ally        `json:"allies"`
+	HitDie              HitDice       `json:"hitDie"` //The hit die of the first class, and the total amount of hit dice. Set by SyncClasses
+	Health              int           `json:"health"` //The max hit points. Set by MigrateHitPoints
+	HitPoints           HitPoints     `json:"hitPoints"`
+	Spells              []Spell       `json:"spells"`
+	Classes             []ClassLevel  `json:"classes"`             //Every class the character has levels in, starting with the first one taken
+	SpellcastingAbility string        `json:"spellcastingAbility"` //Overrides the ability the classes cast with. Empty to use the one of the first spellcasting class
+	SpellSlots          []SpellSlot   `json:"spellSlots"`          //Slots of each spell level, computed from the class levels
+	PactSlots           SpellSlot     `json:"pactSlots"`           //Warlock pact magic slots, which are regained on a short rest
+	Resources           []Resource    `json:"resources"`
+	Attacks             []Attack      `json:"attacks"`
+	Conditions          []Condition   `json:"conditions"`
+	Exhaustion          int           `json:"exhaustion"`        //Exhaustion level, from 0 to 6
+	History             []Event       `json:"history"`           //Things that happened to the character, oldest first
+	Ledger              []Transaction `json:"ledger"`            //Every change made to the coins through a transaction, oldest first
+	Rolls               []RollRecord  `json:"rolls"`             //The latest dice rolls made for the character, oldest first
+	Milestone           bool          `json:"milestone"`         //Levels are handed out by the DM instead of earned with exp
+	LevelHistory        []LevelRecord `json:"levelHistory"`      //Every level-up the character has gone through
+	Version             int           `json:"version"`           //Counts the writes to the stored sheet, so concurrent changes can be detected
+	Deleted             *time.Time    `json:"deleted,omitempty"` //When the sheet was moved to the trash. Nil for sheets in use
 }
 
 //Index holds the data that fills our index page.
 type Index struct {
-	Sheets   []string
+	Sheets   []SheetInfo
+	Message  string //Shown instead of the sheets when there are none, or they couldn't be loaded
 	Title    string
 	LoggedIn bool
 }
 
+//SheetInfo is what a list of sheets shows of each sheet
+type SheetInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	CharacterName string `json:"characterName"`
+}
+
 //SheetPage holds the data that fills the sheet page
 type SheetPage struct {
 	CharacterSheet Sheet
+	Derived        Derived
 	LoggedIn       bool
 }
 
+//SheetForm holds the data that fills the sheet form. When editing, the form starts out filled with the given sheet.
+//When a submitted form had errors, it's filled with the submitted values instead, and the errors are shown by their fields
+type SheetForm struct {
+	CharacterSheet Sheet
+	Edit           bool
+	Errors         FieldErrors
+	Values         map[string][]string
+}
+
+//LevelUpPage holds the data that fills the level-up page
+type LevelUpPage struct {
+	CharacterSheet Sheet
+	NextLevelXP    int    //Exp needed for the next level. 0 at the highest level
+	Blocked        string //Why the character can't level up yet. Empty if it can
+	ConModifier    int
+	Error          string //What went wrong with the last level-up attempt
+}
+
+//Revision is a saved version of a sheet, kept so earlier versions can be looked at and restored
+type Revision struct {
+	Owner       string    `json:"owner"`
+	SheetID     string    `json:"sheetId"`         //ID of the sheet, which the revisions are kept by
+	Name        string    `json:"name"`            //Name the sheet had at this version
+	Version     int       `json:"version"`         //Version the sheet had after the change
+	Date        time.Time `json:"date"`            //When the change was made
+	Description string    `json:"description"`     //Short summary of what changed
+	Sheet       *Sheet    `json:"sheet,omitempty"` //The whole sheet at this version. Left out of revision lists
+}
+
+//RevisionsPage holds the data that fills the revisions page
+type RevisionsPage struct {
+	ID        string     //ID of the sheet
+	Name      string     //Name of the sheet
+	Revisions []Revision //Revisions of the sheet, newest first
+	From      int        //Older version of the shown diff
+	To        int        //Newer version of the shown diff. -1 is the sheet as it is now
+	Changes   []Change   //Fields that differ between the two versions. Nil if no diff is shown
+	Error     string     //What went wrong with the last diff or restore
+}
+
+//TrashedSheet is a sheet in the trash, waiting to be restored or purged
+type TrashedSheet struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	CharacterName string    `json:"characterName"`
+	Deleted       time.Time `json:"deleted"` //When the sheet was moved to the trash
+	PurgeAt       time.Time `json:"purgeAt"` //When the sheet will be purged. Zero if trashed sheets are kept until purged by hand
+}
+
+//TrashPage holds the data that fills the trash page
+type TrashPage struct {
+	Sheets        []TrashedSheet //Sheets in the trash, latest deleted first
+	RetentionDays int            //How many days sheets stay in the trash. 0 keeps them until purged by hand
+	Error         string         //What went wrong with the last restore or purge
+}
+
+//CloneOptions picks the name of a cloned sheet, and what the new character starts over with
+type CloneOptions struct {
+	Name           string `json:"name"`
+	ResetExp       bool   `json:"resetExp"`       //Starts the exp at the beginning of the character's level
+	ResetHitPoints bool   `json:"resetHitPoints"` //Starts at full health, with no hit dice spent
+	ResetCoins     bool   `json:"resetCoins"`     //Starts without coins, and with an empty ledger
+	ResetInventory bool   `json:"resetInventory"` //Starts without items
+}
+
+//ClonePage holds the data that fills the clone page
+type ClonePage struct {
+	ID      string //ID of the sheet being cloned
+	Name    string //Name of the sheet being cloned
+	Options CloneOptions
+	Error   string //What went wrong with the last clone
+}
+
 //DeletePage holds the data that fills the delete page
 type DeletePage struct {
+	SheetID   string
 	SheetName string
 }
 
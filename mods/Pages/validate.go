@@ -0,0 +1,279 @@
+package pages
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//Alignments holds the valid alignment codes
+var Alignments = []string{"LG", "NG", "CG", "LN", "N", "CN", "LE", "NE", "CE"}
+
+//Sizes holds the valid creature sizes, from smallest to largest
+var Sizes = []string{"Tiny", "Small", "Medium", "Large", "Huge", "Gargantuan"}
+
+//AbilityNames holds the names of the six abilities, which are also the names of the saving throws
+var AbilityNames = []string{"Strength", "Dexterity", "Constitution", "Intelligence", "Wisdom", "Charisma"}
+
+//Skills holds the names of the 18 skills
+var Skills = []string{
+	"Acrobatics", "Animal Handling", "Arcana", "Athletics", "Deception", "History",
+	"Insight", "Intimidation", "Investigation", "Medicine", "Nature", "Perception",
+	"Performance", "Persuasion", "Religion", "Sleight of Hand", "Stealth", "Survival",
+}
+
+//ConditionNames holds the names of the conditions
+var ConditionNames = []string{
+	"Blinded", "Charmed", "Deafened", "Frightened", "Grappled", "Incapacitated", "Invisible",
+	"Paralyzed", "Petrified", "Poisoned", "Prone", "Restrained", "Stunned", "Unconscious",
+}
+
+//HitDieNames holds the valid hit dice
+var HitDieNames = []string{"1d6", "1d8", "1d10", "1d12"}
+
+//Recharges holds the ways a resource can recharge. "short" resources also recharge on a long rest
+var Recharges = []string{"short", "long", "dawn", "none"}
+
+//EncumbranceRules holds the ways carried weight can slow a character. "standard" only limits it to the carrying capacity,
+//"variant" also slows it at a third and two thirds of it
+var EncumbranceRules = []string{"standard", "variant"}
+
+//ArmorTypes holds the kinds of armor an item can be
+var ArmorTypes = []string{"light", "medium", "heavy", "shield"}
+
+//AttackAbilities holds the abilities an attack can use. "Finesse" uses the better of strength and dexterity,
+//and "Spell" the spellcasting ability
+var AttackAbilities = []string{"Strength", "Dexterity", "Finesse", "Spell"}
+
+//DamageTypes holds the types of damage
+var DamageTypes = []string{
+	"Acid", "Bludgeoning", "Cold", "Fire", "Force", "Lightning", "Necrotic",
+	"Piercing", "Poison", "Psychic", "Radiant", "Slashing", "Thunder",
+}
+
+//Matches damage dice like "1d8" or "2d6"
+var damageDice = regexp.MustCompile(`^[1-9][0-9]*d[1-9][0-9]*$`)
+
+//FieldErrors maps the json path of a sheet field, like "scores.strength" or "inventory.2", to what is wrong with it
+type FieldErrors map[string]string
+
+//Add records an error for a field, unless the field already has one
+func (e FieldErrors) Add(path string, message string) {
+	if _, ok := e[path]; !ok {
+		e[path] = message
+	}
+}
+
+//Validate checks that the values of the sheet follow the rules of the game. Gives an empty map if the sheet is valid
+func (s Sheet) Validate() FieldErrors {
+	errs := FieldErrors{}
+	required := map[string]string{ //Text fields that must be filled in
+		"name":          s.Name,
+		"characterName": s.CharacterName,
+		"race":          s.Race,
+	}
+	for path, value := range required {
+		if strings.TrimSpace(value) == "" {
+			errs.Add(path, "Is required")
+		}
+	}
+	if len(s.Classes) == 0 {
+		errs.Add("classes", "Needs at least one class")
+	}
+	for i, class := range s.Classes {
+		path := fmt.Sprintf("classes.%d", i)
+		if strings.TrimSpace(class.Name) == "" {
+			errs.Add(path, "Needs a name")
+		} else if class.Level < 1 {
+			errs.Add(path, "Level must be at least 1")
+		} else if !contains(HitDieNames, class.HitDie) {
+			errs.Add(path, fmt.Sprintf("Hit die must be one of %s", strings.Join(HitDieNames, ", ")))
+		} else if class.HitDiceSpent < 0 || class.HitDiceSpent > class.Level {
+			errs.Add(path, "Spent hit dice must be from 0 to the class level")
+		}
+	}
+	checkRange(errs, "level", s.Level, 1, 20)
+	scores := map[string]int{
+		"strength":     s.Scores.Strength,
+		"dexterity":    s.Scores.Dexterity,
+		"constitution": s.Scores.Constitution,
+		"intelligence": s.Scores.Intelligence,
+		"wisdom":       s.Scores.Wisdom,
+		"charisma":     s.Scores.Charisma,
+	}
+	for name, score := range scores {
+		checkRange(errs, "scores."+name, score, 1, 30)
+	}
+	checkAtLeast(errs, "age", s.Age, 0)
+	checkAtLeast(errs, "currentExpirience", s.CurrentExpirience, 0)
+	checkAtLeast(errs, "nextExpirience", s.NextExpirience, 0)
+	checkAtLeast(errs, "speed", s.Speed, 0)
+	checkAtLeast(errs, "ac", s.AC, 0)
+	checkAtLeast(errs, "health", s.Health, 0)
+	checkRange(errs, "hitPoints.current", s.HitPoints.Current, 0, s.HitPoints.Max)
+	checkAtLeast(errs, "hitPoints.temp", s.HitPoints.Temp, 0)
+	checkRange(errs, "exhaustion", s.Exhaustion, 0, 6)
+	for i, condition := range s.Conditions {
+		checkOneOf(errs, fmt.Sprintf("conditions.%d", i), condition.Name, ConditionNames)
+	}
+	checkRange(errs, "hitPoints.deathSaves.successes", s.HitPoints.DeathSaves.Successes, 0, 3)
+	checkRange(errs, "hitPoints.deathSaves.failures", s.HitPoints.DeathSaves.Failures, 0, 3)
+	coins := map[string]int{"cp": s.Money.CP, "sp": s.Money.SP, "ep": s.Money.EP, "gp": s.Money.GP, "pp": s.Money.PP}
+	for name, amount := range coins {
+		checkAtLeast(errs, "money."+name, amount, 0)
+	}
+	checkOneOf(errs, "allignment", s.Allignment, Alignments)
+	checkOneOf(errs, "size", s.Size, Sizes)
+	if s.SpellcastingAbility != "" {
+		checkOneOf(errs, "spellcastingAbility", s.SpellcastingAbility, AbilityNames)
+	}
+	checkAllOf(errs, "saves", s.Saves, AbilityNames)
+	checkAllOf(errs, "proficientSkills", s.ProficientSkills, Skills)
+	checkAllOf(errs, "expertSkills", s.ExpertSkills, Skills)
+	for _, skill := range s.ExpertSkills { //Expertise doubles the proficiency bonus, so it needs proficiency first
+		if !contains(s.ProficientSkills, skill) {
+			errs.Add("expertSkills", fmt.Sprintf("Expertise in %s needs proficiency in it", skill))
+		}
+	}
+	for i, item := range s.Inventory {
+		if strings.TrimSpace(item.Name) == "" {
+			errs.Add(fmt.Sprintf("inventory.%d", i), "Needs a name")
+		} else if item.Amount < 0 {
+			errs.Add(fmt.Sprintf("inventory.%d", i), "Amount can't be negative")
+		} else if item.Armor != "" && !contains(ArmorTypes, item.Armor) {
+			errs.Add(fmt.Sprintf("inventory.%d", i), fmt.Sprintf("Armor must be one of %s", strings.Join(ArmorTypes, ", ")))
+		} else if item.Armor != "" && item.ArmorClass < 1 {
+			errs.Add(fmt.Sprintf("inventory.%d", i), "Armor needs an AC")
+		} else if item.Weight < 0 || item.Value < 0 {
+			errs.Add(fmt.Sprintf("inventory.%d", i), "Weight and value can't be negative")
+		} else if item.Container != "" && !s.inContainer(item.Container, item.Name) {
+			errs.Add(fmt.Sprintf("inventory.%d", i), fmt.Sprintf("%s must be another item in the inventory, and can't be kept inside this item", item.Container))
+		}
+	}
+	if s.Encumbrance != "" {
+		checkOneOf(errs, "encumbrance", s.Encumbrance, EncumbranceRules)
+	}
+	armor, shields := 0, 0
+	for _, item := range s.Inventory {
+		if item.Equipped && item.Armor == "shield" {
+			shields++
+		} else if item.Equipped && item.Armor != "" {
+			armor++
+		}
+	}
+	if armor > 1 {
+		errs.Add("inventory", "Only one suit of armor can be equipped")
+	} else if shields > 1 {
+		errs.Add("inventory", "Only one shield can be equipped")
+	}
+	for i, spell := range s.Spells {
+		if strings.TrimSpace(spell.Name) == "" {
+			errs.Add(fmt.Sprintf("spells.%d", i), "Needs a name")
+		} else if spell.Level < 0 || spell.Level > 9 {
+			errs.Add(fmt.Sprintf("spells.%d", i), "Level must be from 0 to 9")
+		}
+	}
+	for i, slot := range s.SpellSlots {
+		checkRange(errs, fmt.Sprintf("spellSlots.%d", i), slot.Used, 0, slot.Max)
+	}
+	checkRange(errs, "pactSlots", s.PactSlots.Used, 0, s.PactSlots.Max)
+	for i, resource := range s.Resources {
+		path := fmt.Sprintf("resources.%d", i)
+		if strings.TrimSpace(resource.Name) == "" {
+			errs.Add(path, "Needs a name")
+		} else if resource.Max < 0 || resource.Current < 0 || resource.Current > resource.Max {
+			errs.Add(path, "Uses must be from 0 to the max")
+		} else if !contains(Recharges, resource.Recharge) {
+			errs.Add(path, fmt.Sprintf("Recharge must be one of %s", strings.Join(Recharges, ", ")))
+		}
+	}
+	for i, attack := range s.Attacks {
+		path := fmt.Sprintf("attacks.%d", i)
+		if strings.TrimSpace(attack.Name) == "" {
+			errs.Add(path, "Needs a name")
+		} else if !contains(AttackAbilities, attack.Ability) {
+			errs.Add(path, fmt.Sprintf("Ability must be one of %s", strings.Join(AttackAbilities, ", ")))
+		} else if !damageDice.MatchString(attack.Damage) {
+			errs.Add(path, fmt.Sprintf("Damage %q must be dice like 1d8", attack.Damage))
+		} else if !contains(DamageTypes, attack.DamageType) {
+			errs.Add(path, fmt.Sprintf("Damage type must be one of %s", strings.Join(DamageTypes, ", ")))
+		} else if attack.MagicBonus < 0 || attack.MagicBonus > 3 {
+			errs.Add(path, "Magic bonus must be from 0 to 3")
+		}
+	}
+	for i, feat := range s.Feats {
+		if strings.TrimSpace(feat.Name) == "" {
+			errs.Add(fmt.Sprintf("feats.%d", i), "Needs a name")
+		}
+	}
+	for i, ally := range s.Allies {
+		if strings.TrimSpace(ally.Name) == "" {
+			errs.Add(fmt.Sprintf("allies.%d", i), "Needs a name")
+		}
+	}
+	return errs
+}
+
+//Checks that a number is within the given range, both ends included
+func checkRange(errs FieldErrors, path string, value int, min int, max int) {
+	if value < min || value > max {
+		errs.Add(path, fmt.Sprintf("Must be from %d to %d", min, max))
+	}
+}
+
+//Checks that a number is not below the given minimum
+func checkAtLeast(errs FieldErrors, path string, value int, min int) {
+	if value < min {
+		errs.Add(path, fmt.Sprintf("Can't be less than %d", min))
+	}
+}
+
+//Checks that a value is one of the allowed values
+func checkOneOf(errs FieldErrors, path string, value string, allowed []string) {
+	if !contains(allowed, value) {
+		errs.Add(path, fmt.Sprintf("Must be one of %s", strings.Join(allowed, ", ")))
+	}
+}
+
+//Checks that every value in a list is one of the allowed values
+func checkAllOf(errs FieldErrors, path string, values []string, allowed []string) {
+	for _, value := range values {
+		if !contains(allowed, value) {
+			errs.Add(path, fmt.Sprintf("%q is not one of %s", value, strings.Join(allowed, ", ")))
+		}
+	}
+}
+
+//Checks if a list holds the given value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+//Checks that a container is an item in the inventory, and that it isn't kept inside the given item, directly or through other containers
+func (s Sheet) inContainer(container string, item string) bool {
+	for steps := 0; steps <= len(s.Inventory); steps++ { //A chain longer than the inventory has to loop
+		if container == item {
+			return false
+		}
+		found := false
+		for _, other := range s.Inventory {
+			if other.Name == container {
+				container = other.Container
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		if container == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package rules
+
+import (
+	pages "Pages"
+	"fmt"
+)
+
+//MediumDexCap is the most dexterity modifier medium armor adds to AC
+const MediumDexCap = 2
+
+//ArmorClass computes the AC of the character from its equipped armor, shield and items, and lists what it's made of.
+//Without armor the best of 10 + dexterity and the Unarmored Defense of a barbarian or monk is used
+func ArmorClass(sheet pages.Sheet) (int, []string) {
+	dex := Modifier(sheet.Scores.Dexterity)
+	var armor, shield *pages.Item
+	for i := range sheet.Inventory {
+		item := &sheet.Inventory[i]
+		if !item.Equipped || item.Armor == "" {
+			continue
+		}
+		if item.Armor == "shield" && shield == nil {
+			shield = item
+		} else if item.Armor != "shield" && armor == nil {
+			armor = item
+		}
+	}
+	ac := 10 + dex
+	sources := []string{fmt.Sprintf("Unarmored 10%+d", dex)}
+	switch {
+	case armor == nil:
+		if findClass(&sheet, "barbarian") != nil && 10+dex+Modifier(sheet.Scores.Constitution) > ac {
+			ac = 10 + dex + Modifier(sheet.Scores.Constitution)
+			sources = []string{fmt.Sprintf("Unarmored Defense 10%+d%+d", dex, Modifier(sheet.Scores.Constitution))}
+		}
+		if findClass(&sheet, "monk") != nil && shield == nil && 10+dex+Modifier(sheet.Scores.Wisdom) > ac {
+			ac = 10 + dex + Modifier(sheet.Scores.Wisdom)
+			sources = []string{fmt.Sprintf("Unarmored Defense 10%+d%+d", dex, Modifier(sheet.Scores.Wisdom))}
+		}
+	case armor.Armor == "light":
+		ac = armor.ArmorClass + dex
+		sources = []string{fmt.Sprintf("%s %d%+d", armor.Name, armor.ArmorClass, dex)}
+	case armor.Armor == "medium":
+		if dex > MediumDexCap {
+			dex = MediumDexCap
+		}
+		ac = armor.ArmorClass + dex
+		sources = []string{fmt.Sprintf("%s %d%+d", armor.Name, armor.ArmorClass, dex)}
+	default: //Heavy armor doesn't use dexterity
+		ac = armor.ArmorClass
+		sources = []string{fmt.Sprintf("%s %d", armor.Name, armor.ArmorClass)}
+	}
+	if shield != nil {
+		ac += shield.ArmorClass
+		sources = append(sources, fmt.Sprintf("%s %+d", shield.Name, shield.ArmorClass))
+	}
+	for _, item := range sheet.Inventory {
+		if item.Equipped && item.ACBonus != 0 {
+			ac += item.ACBonus
+			sources = append(sources, fmt.Sprintf("%s %+d", item.Name, item.ACBonus))
+		}
+	}
+	return ac, sources
+}
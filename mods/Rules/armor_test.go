@@ -0,0 +1,49 @@
+package rules
+
+import (
+	pages "Pages"
+	"reflect"
+	"testing"
+)
+
+func TestArmorClass(t *testing.T) {
+	chain := pages.Item{Name: "Chain mail", Equipped: true, Armor: "heavy", ArmorClass: 16}
+	leather := pages.Item{Name: "Leather", Equipped: true, Armor: "light", ArmorClass: 11}
+	halfPlate := pages.Item{Name: "Half plate", Equipped: true, Armor: "medium", ArmorClass: 15}
+	shield := pages.Item{Name: "Shield", Equipped: true, Armor: "shield", ArmorClass: 2}
+	ring := pages.Item{Name: "Ring of protection", Equipped: true, ACBonus: 1}
+	barbarian := []pages.ClassLevel{{Name: "Barbarian", Level: 1}}
+	monk := []pages.ClassLevel{{Name: "Monk", Level: 1}}
+	tests := []struct {
+		name    string
+		sheet   pages.Sheet
+		want    int
+		sources []string
+	}{
+		{"unarmored", pages.Sheet{Scores: pages.Abilities{Dexterity: 14}}, 12, []string{"Unarmored 10+2"}},
+		{"light", pages.Sheet{Scores: pages.Abilities{Dexterity: 18}, Inventory: []pages.Item{leather}}, 15, []string{"Leather 11+4"}},
+		{"medium caps dexterity", pages.Sheet{Scores: pages.Abilities{Dexterity: 18}, Inventory: []pages.Item{halfPlate}}, 17, []string{"Half plate 15+2"}},
+		{"heavy ignores dexterity", pages.Sheet{Scores: pages.Abilities{Dexterity: 8}, Inventory: []pages.Item{chain, shield, ring}}, 19,
+			[]string{"Chain mail 16", "Shield +2", "Ring of protection +1"}},
+		{"unequipped armor", pages.Sheet{Scores: pages.Abilities{Dexterity: 10}, Inventory: []pages.Item{{Name: "Plate", Armor: "heavy", ArmorClass: 18}}}, 10, []string{"Unarmored 10+0"}},
+		{"barbarian", pages.Sheet{Classes: barbarian, Scores: pages.Abilities{Dexterity: 14, Constitution: 16}, Inventory: []pages.Item{shield}}, 17,
+			[]string{"Unarmored Defense 10+2+3", "Shield +2"}},
+		{"monk", pages.Sheet{Classes: monk, Scores: pages.Abilities{Dexterity: 16, Wisdom: 14}}, 15, []string{"Unarmored Defense 10+3+2"}},
+		{"monk with a shield", pages.Sheet{Classes: monk, Scores: pages.Abilities{Dexterity: 16, Wisdom: 14}, Inventory: []pages.Item{shield}}, 15,
+			[]string{"Unarmored 10+3", "Shield +2"}},
+	}
+	for _, test := range tests {
+		ac, sources := ArmorClass(test.sheet)
+		if ac != test.want || !reflect.DeepEqual(sources, test.sources) {
+			t.Errorf("%s: ArmorClass gave %d from %q, want %d from %q", test.name, ac, sources, test.want, test.sources)
+		}
+	}
+}
+
+func TestDeriveACOverride(t *testing.T) {
+	sheet := pages.Sheet{AC: 20, Scores: pages.Abilities{Dexterity: 12}}
+	derived := Derive(sheet)
+	if derived.AC != 20 || derived.ComputedAC != 11 || !derived.ACOverridden {
+		t.Errorf("the AC is %d, computed %d, overridden %v", derived.AC, derived.ComputedAC, derived.ACOverridden)
+	}
+}
@@ -0,0 +1,56 @@
+package rules
+
+import (
+	pages "Pages"
+	"fmt"
+)
+
+//AttackAbility gives the ability score an attack uses. Finesse attacks use the better of strength and dexterity,
+//and spell attacks the spellcasting ability, which is empty if the character doesn't cast spells
+func AttackAbility(sheet pages.Sheet, attack pages.Attack) string {
+	switch attack.Ability {
+	case "Finesse":
+		if Modifier(sheet.Scores.Dexterity) > Modifier(sheet.Scores.Strength) {
+			return "Dexterity"
+		}
+		return "Strength"
+	case "Spell":
+		return CastingAbility(sheet)
+	default:
+		return attack.Ability
+	}
+}
+
+//Attacks computes the to-hit bonus and damage of each attack on the sheet. Spell attacks are always proficient,
+//and don't add the ability modifier to the damage
+func Attacks(sheet pages.Sheet) []pages.AttackBonus {
+	attacks := []pages.AttackBonus{}
+	bonus := ProficiencyBonus(sheet.Level)
+	scores := Scores(sheet)
+	for _, attack := range sheet.Attacks {
+		computed := pages.AttackBonus{
+			Name:       attack.Name,
+			Ability:    AttackAbility(sheet, attack),
+			DamageType: attack.DamageType,
+			Range:      attack.Range,
+		}
+		modifier := 0
+		if computed.Ability != "" {
+			modifier = Modifier(scores[computed.Ability])
+		}
+		damage := modifier + attack.MagicBonus
+		computed.ToHit = modifier + attack.MagicBonus
+		if attack.Ability == "Spell" {
+			computed.ToHit += bonus
+			damage = attack.MagicBonus
+		} else if attack.Proficient {
+			computed.ToHit += bonus
+		}
+		computed.Damage = attack.Damage
+		if damage != 0 {
+			computed.Damage += fmt.Sprintf("%+d", damage)
+		}
+		attacks = append(attacks, computed)
+	}
+	return attacks
+}
@@ -0,0 +1,58 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+)
+
+func TestAttacks(t *testing.T) {
+	sheet := pages.Sheet{
+		Level:   5,
+		Classes: []pages.ClassLevel{{Name: "Wizard", Level: 5, HitDie: "1d6"}},
+		Scores:  pages.Abilities{Strength: 16, Dexterity: 18, Intelligence: 14},
+		Attacks: []pages.Attack{
+			{Name: "Longsword", Ability: "Strength", Proficient: true, Damage: "1d8", DamageType: "Slashing", MagicBonus: 1},
+			{Name: "Rapier", Ability: "Finesse", Proficient: true, Damage: "1d8"},
+			{Name: "Fire Bolt", Ability: "Spell", Damage: "1d10", DamageType: "Fire", Range: "120ft"},
+			{Name: "Club", Ability: "Strength", Damage: "1d4"},
+		},
+	}
+	want := []pages.AttackBonus{
+		{Name: "Longsword", Ability: "Strength", ToHit: 7, Damage: "1d8+4", DamageType: "Slashing"},
+		{Name: "Rapier", Ability: "Dexterity", ToHit: 7, Damage: "1d8+4"},
+		{Name: "Fire Bolt", Ability: "Intelligence", ToHit: 5, Damage: "1d10", DamageType: "Fire", Range: "120ft"},
+		{Name: "Club", Ability: "Strength", ToHit: 3, Damage: "1d4+3"},
+	}
+	attacks := Attacks(sheet)
+	if len(attacks) != len(want) {
+		t.Fatalf("Attacks gave %+v", attacks)
+	}
+	for i := range want {
+		if attacks[i] != want[i] {
+			t.Errorf("attack %d is %+v, want %+v", i, attacks[i], want[i])
+		}
+	}
+}
+
+func TestAttackAbility(t *testing.T) {
+	tests := []struct {
+		name   string
+		sheet  pages.Sheet
+		attack pages.Attack
+		want   string
+	}{
+		{"finesse with strength", pages.Sheet{Scores: pages.Abilities{Strength: 16, Dexterity: 14}}, pages.Attack{Ability: "Finesse"}, "Strength"},
+		{"finesse tie", pages.Sheet{Scores: pages.Abilities{Strength: 14, Dexterity: 15}}, pages.Attack{Ability: "Finesse"}, "Strength"},
+		{"spell without casting", pages.Sheet{Classes: []pages.ClassLevel{{Name: "Fighter", Level: 1}}}, pages.Attack{Ability: "Spell"}, ""},
+		{"dexterity", pages.Sheet{}, pages.Attack{Ability: "Dexterity"}, "Dexterity"},
+	}
+	for _, test := range tests {
+		if got := AttackAbility(test.sheet, test.attack); got != test.want {
+			t.Errorf("%s: AttackAbility gave %q, want %q", test.name, got, test.want)
+		}
+	}
+	weak := pages.Sheet{Scores: pages.Abilities{Strength: 8}, Attacks: []pages.Attack{{Ability: "Strength", Damage: "1d4"}}}
+	if attack := Attacks(weak)[0]; attack.ToHit != -1 || attack.Damage != "1d4-1" {
+		t.Errorf("a weak attack is %+v", attack)
+	}
+}
@@ -0,0 +1,31 @@
+package rules
+
+import pages "Pages"
+
+//ResetClone clears what a copy of a sheet shouldn't start with, as picked by the options
+func ResetClone(sheet *pages.Sheet, options pages.CloneOptions) {
+	if options.ResetExp {
+		level := sheet.Level
+		if level < 1 {
+			level = 1
+		} else if level > MaxLevel {
+			level = MaxLevel
+		}
+		sheet.CurrentExpirience = XPThresholds[level-1]
+		sheet.NextExpirience = NextLevelXP(level)
+	}
+	if options.ResetHitPoints {
+		sheet.HitPoints = pages.HitPoints{Max: sheet.HitPoints.Max}
+		sheet.HitPoints.Current = MaxHitPoints(*sheet)
+		for i := range sheet.Classes {
+			sheet.Classes[i].HitDiceSpent = 0
+		}
+	}
+	if options.ResetCoins {
+		sheet.Money = pages.Coin{}
+		sheet.Ledger = nil
+	}
+	if options.ResetInventory {
+		sheet.Inventory = nil
+	}
+}
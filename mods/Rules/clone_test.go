@@ -0,0 +1,52 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+)
+
+//Makes a level 5 fighter with exp past its level, hurt, and carrying coins and gear
+func cloneSheet() pages.Sheet {
+	return pages.Sheet{
+		Level:             5,
+		Classes:           []pages.ClassLevel{{Name: "Fighter", Level: 5, HitDie: "1d10", HitDiceSpent: 3}},
+		CurrentExpirience: 9000,
+		NextExpirience:    14000,
+		HitPoints:         pages.HitPoints{Max: 44, Current: 0, Temp: 5, Stable: true, DeathSaves: pages.DeathSaves{Successes: 3}},
+		Money:             pages.Coin{GP: 40},
+		Ledger:            []pages.Transaction{{Action: "receive", Description: "Loot"}},
+		Inventory:         []pages.Item{{Name: "Rope", Amount: 1}},
+		Exhaustion:        4,
+	}
+}
+
+func TestResetClone(t *testing.T) {
+	sheet := cloneSheet()
+	ResetClone(&sheet, pages.CloneOptions{ResetExp: true, ResetHitPoints: true, ResetCoins: true, ResetInventory: true})
+	if sheet.CurrentExpirience != 6500 || sheet.NextExpirience != 14000 {
+		t.Errorf("the exp is %d of %d, want 6500 of 14000", sheet.CurrentExpirience, sheet.NextExpirience)
+	}
+	if sheet.HitPoints != (pages.HitPoints{Max: 44, Current: 22}) || sheet.Classes[0].HitDiceSpent != 0 { //Exhaustion 4 halves the max
+		t.Errorf("the hit points are %+v with %d hit dice spent", sheet.HitPoints, sheet.Classes[0].HitDiceSpent)
+	}
+	if sheet.Money != (pages.Coin{}) || sheet.Ledger != nil || sheet.Inventory != nil {
+		t.Errorf("the coins, ledger and inventory are %+v, %+v and %+v", sheet.Money, sheet.Ledger, sheet.Inventory)
+	}
+	if sheet.Level != 5 || sheet.Exhaustion != 4 {
+		t.Error("a reset changed what it doesn't reset")
+	}
+}
+
+func TestResetCloneNothing(t *testing.T) {
+	sheet := cloneSheet()
+	ResetClone(&sheet, pages.CloneOptions{})
+	want := cloneSheet()
+	if sheet.CurrentExpirience != want.CurrentExpirience || sheet.HitPoints != want.HitPoints || sheet.Money != want.Money || len(sheet.Inventory) != 1 {
+		t.Errorf("a clone without resets changed to %+v", sheet)
+	}
+	outOfRange := pages.Sheet{Level: 0}
+	ResetClone(&outOfRange, pages.CloneOptions{ResetExp: true})
+	if outOfRange.CurrentExpirience != 0 || outOfRange.NextExpirience != 300 {
+		t.Errorf("a sheet without a level got exp %d of %d", outOfRange.CurrentExpirience, outOfRange.NextExpirience)
+	}
+}
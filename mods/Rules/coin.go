@@ -0,0 +1,190 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ErrBadCoins is returned when an amount of coins can't be read
+var ErrBadCoins = errors.New(`amounts must be whole numbers of coins, like "12 gp 5 sp"`)
+
+//ErrNotEnoughCoins is returned when the character can't pay what it's spending
+var ErrNotEnoughCoins = errors.New("the character doesn't have enough coins")
+
+//ErrBadCoinAction is returned for a coin action we don't know
+var ErrBadCoinAction = errors.New("the action must be spend, receive or consolidate")
+
+//Denominations holds the short names of the coins, from smallest to largest
+var Denominations = []string{"cp", "sp", "ep", "gp", "pp"}
+
+//CoinValues maps each coin to its worth in copper pieces
+var CoinValues = map[string]int{"cp": 1, "sp": 10, "ep": 50, "gp": 100, "pp": 1000}
+
+//Coins that change is given in, from largest to smallest. Electrum is left out, as it's rarely handed out
+var changeCoins = []string{"pp", "gp", "sp", "cp"}
+
+//CoinAction is a change to the coins of a character
+type CoinAction struct {
+	Action      string `json:"action"` //"spend", "receive" or "consolidate"
+	Amount      string `json:"amount"` //Like "12 gp 5 sp". Not used by "consolidate"
+	Description string `json:"description"`
+}
+
+//ParseCoins reads an amount like "12 gp 5 sp" or "3gp, 2cp". A number without a coin counts as gold
+func ParseCoins(amount string) (pages.Coin, error) {
+	coins := map[string]int{}
+	fields := strings.Fields(strings.NewReplacer(",", " ", "+", " ").Replace(strings.ToLower(amount)))
+	if len(fields) == 0 {
+		return pages.Coin{}, ErrBadCoins
+	}
+	for i := 0; i < len(fields); i++ {
+		number, coin := fields[i], "gp"
+		for _, name := range Denominations { //The coin can be written right after the number, like "12gp"
+			if strings.HasSuffix(number, name) {
+				number, coin = strings.TrimSuffix(number, name), name
+			}
+		}
+		if number == fields[i] && i+1 < len(fields) && CoinValues[fields[i+1]] > 0 {
+			coin = fields[i+1]
+			i++
+		}
+		count, err := strconv.Atoi(number)
+		if err != nil || count < 0 {
+			return pages.Coin{}, ErrBadCoins
+		}
+		coins[coin] += count
+	}
+	return toCoin(coins), nil
+}
+
+//FormatCoins writes an amount of coins like "12 gp 5 sp", largest first
+func FormatCoins(coin pages.Coin) string {
+	counts := fromCoin(coin)
+	parts := []string{}
+	for i := len(Denominations) - 1; i >= 0; i-- {
+		if counts[Denominations[i]] != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[Denominations[i]], Denominations[i]))
+		}
+	}
+	if len(parts) == 0 {
+		return "0 gp"
+	}
+	return strings.Join(parts, " ")
+}
+
+//CopperValue gives the worth of an amount of coins in copper pieces
+func CopperValue(coin pages.Coin) int {
+	value := 0
+	for name, count := range fromCoin(coin) {
+		value += count * CoinValues[name]
+	}
+	return value
+}
+
+//WealthGP gives the worth of the character's coins in gold pieces
+func WealthGP(sheet pages.Sheet) float64 {
+	return float64(CopperValue(sheet.Money)) / float64(CoinValues["gp"])
+}
+
+//ApplyCoins spends, receives or consolidates the character's coins, and records it in the ledger
+func ApplyCoins(sheet *pages.Sheet, action CoinAction, now time.Time) (pages.Transaction, error) {
+	amount := pages.Coin{}
+	if action.Action != "consolidate" {
+		var err error
+		if amount, err = ParseCoins(action.Amount); err != nil {
+			return pages.Transaction{}, err
+		}
+	}
+	switch action.Action {
+	case "spend":
+		money, err := Spend(sheet.Money, amount)
+		if err != nil {
+			return pages.Transaction{}, err
+		}
+		sheet.Money = money
+	case "receive":
+		sheet.Money = toCoin(addCounts(fromCoin(sheet.Money), fromCoin(amount)))
+	case "consolidate":
+		sheet.Money = Consolidate(sheet.Money)
+	default:
+		return pages.Transaction{}, ErrBadCoinAction
+	}
+	transaction := pages.Transaction{Date: now, Action: action.Action, Amount: amount, Description: action.Description, Balance: sheet.Money}
+	sheet.Ledger = append(sheet.Ledger, transaction)
+	return transaction, nil
+}
+
+//Spend takes an amount out of a purse, making change when the exact coins aren't there. The coins asked for are paid
+//with the same coins first, and what is left with the smallest coins, breaking a larger coin if needed
+func Spend(purse pages.Coin, amount pages.Coin) (pages.Coin, error) {
+	if CopperValue(purse) < CopperValue(amount) {
+		return purse, ErrNotEnoughCoins
+	}
+	counts, owed := fromCoin(purse), fromCoin(amount)
+	remaining := 0 //Copper still owed after paying with the same coins
+	for _, name := range Denominations {
+		paid := owed[name]
+		if counts[name] < paid {
+			paid = counts[name]
+		}
+		counts[name] -= paid
+		remaining += (owed[name] - paid) * CoinValues[name]
+	}
+	for _, name := range Denominations {
+		used := remaining / CoinValues[name]
+		if counts[name] < used {
+			used = counts[name]
+		}
+		counts[name] -= used
+		remaining -= used * CoinValues[name]
+	}
+	for _, name := range Denominations { //Every coin left is worth more than what is still owed, so breaking one pays it
+		if remaining == 0 {
+			break
+		}
+		if counts[name] > 0 {
+			counts[name]--
+			counts = addCounts(counts, change(CoinValues[name]-remaining))
+			remaining = 0
+		}
+	}
+	return toCoin(counts), nil
+}
+
+//Consolidate trades the coins of a purse for as few coins as possible, without using electrum
+func Consolidate(purse pages.Coin) pages.Coin {
+	return toCoin(change(CopperValue(purse)))
+}
+
+//Gives an amount of copper in as few coins as possible, without using electrum
+func change(copper int) map[string]int {
+	counts := map[string]int{}
+	for _, name := range changeCoins {
+		counts[name] = copper / CoinValues[name]
+		copper %= CoinValues[name]
+	}
+	return counts
+}
+
+//Adds the counts of two sets of coins together
+func addCounts(counts map[string]int, other map[string]int) map[string]int {
+	sum := map[string]int{}
+	for _, name := range Denominations {
+		sum[name] = counts[name] + other[name]
+	}
+	return sum
+}
+
+//Gives the counts of each coin in a purse, keyed by their short names
+func fromCoin(coin pages.Coin) map[string]int {
+	return map[string]int{"cp": coin.CP, "sp": coin.SP, "ep": coin.EP, "gp": coin.GP, "pp": coin.PP}
+}
+
+//Makes a purse from the counts of each coin
+func toCoin(counts map[string]int) pages.Coin {
+	return pages.Coin{CP: counts["cp"], SP: counts["sp"], EP: counts["ep"], GP: counts["gp"], PP: counts["pp"]}
+}
@@ -0,0 +1,77 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+	"time"
+)
+
+func TestParseCoins(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   pages.Coin
+		ok     bool
+	}{
+		{"12 gp 5 sp", pages.Coin{GP: 12, SP: 5}, true},
+		{"3gp, 2cp", pages.Coin{GP: 3, CP: 2}, true},
+		{"7", pages.Coin{GP: 7}, true},
+		{"1 pp + 1 ep", pages.Coin{PP: 1, EP: 1}, true},
+		{"", pages.Coin{}, false},
+		{"5 xp", pages.Coin{}, false},
+		{"-5 gp", pages.Coin{}, false},
+	}
+	for _, test := range tests {
+		got, err := ParseCoins(test.amount)
+		if (err == nil) != test.ok || (test.ok && got != test.want) {
+			t.Errorf("ParseCoins(%q) = %+v, %v, want %+v", test.amount, got, err, test.want)
+		}
+	}
+}
+
+func TestSpend(t *testing.T) {
+	tests := []struct {
+		purse, amount, want pages.Coin
+	}{
+		{pages.Coin{GP: 10, SP: 5}, pages.Coin{GP: 2, SP: 5}, pages.Coin{GP: 8}},
+		{pages.Coin{GP: 1}, pages.Coin{SP: 3}, pages.Coin{SP: 7}},                 //Breaks the gold piece for change
+		{pages.Coin{CP: 50, GP: 1}, pages.Coin{SP: 3}, pages.Coin{CP: 20, GP: 1}}, //Pays with the smallest coins first
+		{pages.Coin{PP: 1}, pages.Coin{CP: 1}, pages.Coin{GP: 9, SP: 9, CP: 9}},
+	}
+	for _, test := range tests {
+		got, err := Spend(test.purse, test.amount)
+		if err != nil || got != test.want {
+			t.Errorf("Spend(%+v, %+v) = %+v, %v, want %+v", test.purse, test.amount, got, err, test.want)
+		}
+		if CopperValue(got) != CopperValue(test.purse)-CopperValue(test.amount) {
+			t.Errorf("Spend(%+v, %+v) lost or made money", test.purse, test.amount)
+		}
+	}
+	if _, err := Spend(pages.Coin{GP: 1}, pages.Coin{GP: 1, CP: 1}); err != ErrNotEnoughCoins {
+		t.Errorf("spending more than the purse holds gave %v, want ErrNotEnoughCoins", err)
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	got := Consolidate(pages.Coin{CP: 1234, SP: 15, EP: 2})
+	want := pages.Coin{PP: 1, GP: 4, SP: 8, CP: 4} //1234 + 150 + 100 copper
+	if got != want {
+		t.Errorf("Consolidate gave %+v, want %+v", got, want)
+	}
+}
+
+func TestApplyCoins(t *testing.T) {
+	sheet := pages.Sheet{Money: pages.Coin{GP: 5}}
+	now := time.Now()
+	if _, err := ApplyCoins(&sheet, CoinAction{Action: "receive", Amount: "2 gp"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ApplyCoins(&sheet, CoinAction{Action: "spend", Amount: "8 gp"}, now); err != ErrNotEnoughCoins {
+		t.Errorf("overspending gave %v, want ErrNotEnoughCoins", err)
+	}
+	if _, err := ApplyCoins(&sheet, CoinAction{Action: "steal", Amount: "1 gp"}, now); err != ErrBadCoinAction {
+		t.Errorf("an unknown action gave %v, want ErrBadCoinAction", err)
+	}
+	if sheet.Money != (pages.Coin{GP: 7}) || len(sheet.Ledger) != 1 || sheet.Ledger[0].Balance != sheet.Money {
+		t.Errorf("after receiving 2 gp the money is %+v and the ledger %+v", sheet.Money, sheet.Ledger)
+	}
+}
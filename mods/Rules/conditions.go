@@ -0,0 +1,129 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+	"fmt"
+)
+
+//ErrBadCondition is returned for a condition change we don't know, or a condition that isn't one of the rules
+var ErrBadCondition = errors.New("the action must be add, remove or exhaustion, with a condition from the rules and exhaustion from 0 to 6")
+
+//ConditionEffects holds what each condition does
+var ConditionEffects = map[string][]string{
+	"Blinded":       {"Can't see, and fails checks that need sight", "Attacks against it have advantage, and its attacks have disadvantage"},
+	"Charmed":       {"Can't attack the charmer", "The charmer has advantage on social checks against it"},
+	"Deafened":      {"Can't hear, and fails checks that need hearing"},
+	"Frightened":    {"Disadvantage on checks and attacks while the source of its fear is in sight", "Can't move closer to the source of its fear"},
+	"Grappled":      {"Speed is 0"},
+	"Incapacitated": {"Can't take actions or reactions"},
+	"Invisible":     {"Attacks against it have disadvantage, and its attacks have advantage"},
+	"Paralyzed":     {"Incapacitated, and can't move or speak", "Fails strength and dexterity saves", "Attacks against it have advantage, and hits within 5 feet are critical hits"},
+	"Petrified":     {"Incapacitated, and can't move or speak", "Fails strength and dexterity saves", "Resistant to all damage, and immune to poison and disease"},
+	"Poisoned":      {"Disadvantage on attacks and ability checks"},
+	"Prone":         {"Disadvantage on attacks", "Attacks within 5 feet have advantage against it, and attacks from further away have disadvantage"},
+	"Restrained":    {"Speed is 0", "Disadvantage on attacks and dexterity saves", "Attacks against it have advantage"},
+	"Stunned":       {"Incapacitated, can't move, and can only speak falteringly", "Fails strength and dexterity saves", "Attacks against it have advantage"},
+	"Unconscious":   {"Incapacitated, can't move or speak, and drops what it's holding", "Fails strength and dexterity saves", "Attacks against it have advantage, and hits within 5 feet are critical hits"},
+}
+
+//ExhaustionEffects holds what each level of exhaustion adds to the levels below it. The effect of level n is at index n-1
+var ExhaustionEffects = []string{
+	"Disadvantage on ability checks",
+	"Speed halved",
+	"Disadvantage on attacks and saving throws",
+	"Hit point maximum halved",
+	"Speed is 0",
+	"Death",
+}
+
+//Conditions that stop the character from moving
+var stopMoving = []string{"Grappled", "Restrained", "Paralyzed", "Petrified", "Stunned", "Unconscious"}
+
+//ConditionAction is a change to the conditions of a character
+type ConditionAction struct {
+	Action    string          `json:"action"` //"add", "remove" or "exhaustion"
+	Condition pages.Condition `json:"condition"`
+	Amount    int             `json:"amount"` //The new exhaustion level
+}
+
+//ApplyCondition adds or removes a condition, or sets the exhaustion level. Exhaustion 6 kills the character
+func ApplyCondition(sheet *pages.Sheet, action ConditionAction) error {
+	switch action.Action {
+	case "add":
+		if !contains(pages.ConditionNames, action.Condition.Name) {
+			return ErrBadCondition
+		}
+		sheet.Conditions = append(sheet.Conditions, action.Condition)
+	case "remove":
+		conditions := []pages.Condition{}
+		for _, condition := range sheet.Conditions {
+			if condition.Name != action.Condition.Name {
+				conditions = append(conditions, condition)
+			}
+		}
+		sheet.Conditions = conditions
+	case "exhaustion":
+		if action.Amount < 0 || action.Amount > len(ExhaustionEffects) {
+			return ErrBadCondition
+		}
+		sheet.Exhaustion = action.Amount
+		if sheet.Exhaustion == len(ExhaustionEffects) {
+			sheet.HitPoints.Dead = true
+		}
+		if sheet.HitPoints.Current > MaxHitPoints(*sheet) {
+			sheet.HitPoints.Current = MaxHitPoints(*sheet)
+		}
+	default:
+		return ErrBadCondition
+	}
+	return nil
+}
+
+//MaxHitPoints gives the hit point maximum of the character, which exhaustion can lower
+func MaxHitPoints(sheet pages.Sheet) int {
+	if sheet.Exhaustion >= 4 {
+		return sheet.HitPoints.Max / 2
+	}
+	return sheet.HitPoints.Max
+}
+
+//Speed gives the speed of the character after exhaustion, conditions and encumbrance
+func Speed(sheet pages.Sheet) int {
+	if sheet.Exhaustion >= 5 {
+		return 0
+	}
+	for _, condition := range sheet.Conditions {
+		if contains(stopMoving, condition.Name) {
+			return 0
+		}
+	}
+	_, drop := Encumbrance(sheet)
+	speed := sheet.Speed - drop
+	if speed < 0 {
+		speed = 0
+	}
+	if sheet.Exhaustion >= 2 {
+		return speed / 2
+	}
+	return speed
+}
+
+//Effects lists what the active conditions, exhaustion and encumbrance do
+func Effects(sheet pages.Sheet) []string {
+	effects := []string{}
+	for _, condition := range sheet.Conditions {
+		for _, effect := range ConditionEffects[condition.Name] {
+			effects = append(effects, fmt.Sprintf("%s: %s", condition.Name, effect))
+		}
+	}
+	for i := 0; i < sheet.Exhaustion && i < len(ExhaustionEffects); i++ {
+		effects = append(effects, fmt.Sprintf("Exhaustion %d: %s", i+1, ExhaustionEffects[i]))
+	}
+	if state, drop := Encumbrance(sheet); state == "Heavily encumbered" {
+		effects = append(effects, fmt.Sprintf("%s: Speed drops by %dft, and disadvantage on checks, attacks and saves that use strength, dexterity or constitution", state, drop))
+	} else if state != "" {
+		effects = append(effects, fmt.Sprintf("%s: Speed drops to %dft", state, sheet.Speed-drop))
+	}
+	return effects
+}
@@ -0,0 +1,79 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+)
+
+func TestApplyCondition(t *testing.T) {
+	sheet := pages.Sheet{HitPoints: pages.HitPoints{Max: 20, Current: 18}}
+	for _, action := range []ConditionAction{
+		{Action: "add", Condition: pages.Condition{Name: "Poisoned", Source: "Spider"}},
+		{Action: "add", Condition: pages.Condition{Name: "Prone"}},
+		{Action: "remove", Condition: pages.Condition{Name: "Poisoned"}},
+	} {
+		if err := ApplyCondition(&sheet, action); err != nil {
+			t.Fatalf("%s of %s gave %v", action.Action, action.Condition.Name, err)
+		}
+	}
+	if len(sheet.Conditions) != 1 || sheet.Conditions[0].Name != "Prone" {
+		t.Errorf("the conditions are %+v", sheet.Conditions)
+	}
+	if err := ApplyCondition(&sheet, ConditionAction{Action: "exhaustion", Amount: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if sheet.Exhaustion != 4 || sheet.HitPoints.Current != 10 || MaxHitPoints(sheet) != 10 {
+		t.Errorf("exhaustion 4 left %d of %d hit points", sheet.HitPoints.Current, MaxHitPoints(sheet))
+	}
+	ApplyCondition(&sheet, ConditionAction{Action: "exhaustion", Amount: 6})
+	if !sheet.HitPoints.Dead {
+		t.Error("exhaustion 6 didn't kill the character")
+	}
+}
+
+func TestApplyConditionRejected(t *testing.T) {
+	for _, action := range []ConditionAction{
+		{Action: "add", Condition: pages.Condition{Name: "Sleepy"}},
+		{Action: "exhaustion", Amount: 7},
+		{Action: "exhaustion", Amount: -1},
+		{Action: "cure"},
+	} {
+		sheet := pages.Sheet{}
+		if err := ApplyCondition(&sheet, action); err != ErrBadCondition {
+			t.Errorf("%+v gave %v, want ErrBadCondition", action, err)
+		}
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		sheet pages.Sheet
+		want  int
+	}{
+		{"normal", pages.Sheet{Speed: 30}, 30},
+		{"exhaustion 2", pages.Sheet{Speed: 30, Exhaustion: 2}, 15},
+		{"exhaustion 5", pages.Sheet{Speed: 30, Exhaustion: 5}, 0},
+		{"grappled", pages.Sheet{Speed: 30, Conditions: []pages.Condition{{Name: "Grappled"}}}, 0},
+		{"prone", pages.Sheet{Speed: 30, Conditions: []pages.Condition{{Name: "Prone"}}}, 30},
+	}
+	for _, test := range tests {
+		if got := Speed(test.sheet); got != test.want {
+			t.Errorf("%s: Speed gave %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEffects(t *testing.T) {
+	sheet := pages.Sheet{Exhaustion: 2, Conditions: []pages.Condition{{Name: "Deafened"}}}
+	want := []string{"Deafened: Can't hear, and fails checks that need hearing", "Exhaustion 1: Disadvantage on ability checks", "Exhaustion 2: Speed halved"}
+	effects := Effects(sheet)
+	if len(effects) != len(want) {
+		t.Fatalf("Effects gave %q, want %q", effects, want)
+	}
+	for i := range want {
+		if effects[i] != want[i] {
+			t.Errorf("effect %d is %q, want %q", i, effects[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package rules
+
+import (
+	pages "Pages"
+	"strings"
+)
+
+//SkillAbilities maps each skill to the ability it uses
+var SkillAbilities = map[string]string{
+	"Acrobatics":      "Dexterity",
+	"Animal Handling": "Wisdom",
+	"Arcana":          "Intelligence",
+	"Athletics":       "Strength",
+	"Deception":       "Charisma",
+	"History":         "Intelligence",
+	"Insight":         "Wisdom",
+	"Intimidation":    "Charisma",
+	"Investigation":   "Intelligence",
+	"Medicine":        "Wisdom",
+	"Nature":          "Intelligence",
+	"Perception":      "Wisdom",
+	"Performance":     "Charisma",
+	"Persuasion":      "Charisma",
+	"Religion":        "Intelligence",
+	"Sleight of Hand": "Dexterity",
+	"Stealth":         "Dexterity",
+	"Survival":        "Wisdom",
+}
+
+//CastingAbilities maps each spellcasting class, in lower case, to the ability it casts with
+var CastingAbilities = map[string]string{
+	"artificer": "Intelligence",
+	"bard":      "Charisma",
+	"cleric":    "Wisdom",
+	"druid":     "Wisdom",
+	"paladin":   "Charisma",
+	"ranger":    "Wisdom",
+	"sorcerer":  "Charisma",
+	"warlock":   "Charisma",
+	"wizard":    "Intelligence",
+}
+
+//Modifier gives the ability modifier of an ability score
+func Modifier(score int) int {
+	diff := score - 10
+	if diff < 0 { //Round down for scores below 10 as well
+		diff--
+	}
+	return diff / 2
+}
+
+//ProficiencyBonus gives the proficiency bonus of a character of the given level
+func ProficiencyBonus(level int) int {
+	if level < 1 {
+		level = 1
+	}
+	return 2 + (level-1)/4
+}
+
+//Scores gives the ability scores of a sheet keyed by ability name
+func Scores(sheet pages.Sheet) map[string]int {
+	return map[string]int{
+		"Strength":     sheet.Scores.Strength,
+		"Dexterity":    sheet.Scores.Dexterity,
+		"Constitution": sheet.Scores.Constitution,
+		"Intelligence": sheet.Scores.Intelligence,
+		"Wisdom":       sheet.Scores.Wisdom,
+		"Charisma":     sheet.Scores.Charisma,
+	}
+}
+
+//ClassHitDice maps each class, in lower case, to its hit die
+var ClassHitDice = map[string]string{
+	"artificer": "1d8",
+	"barbarian": "1d12",
+	"bard":      "1d8",
+	"cleric":    "1d8",
+	"druid":     "1d8",
+	"fighter":   "1d10",
+	"monk":      "1d8",
+	"paladin":   "1d10",
+	"ranger":    "1d10",
+	"rogue":     "1d8",
+	"sorcerer":  "1d6",
+	"warlock":   "1d8",
+	"wizard":    "1d6",
+}
+
+//CastingAbility gives the ability the character casts spells with, or an empty string if none of its classes cast spells.
+//A multiclassed caster uses the ability of its first spellcasting class, unless the sheet picks another one
+func CastingAbility(sheet pages.Sheet) string {
+	if sheet.SpellcastingAbility != "" {
+		return sheet.SpellcastingAbility
+	}
+	for _, class := range sheet.Classes {
+		if ability := CastingAbilities[classKey(class.Name)]; ability != "" {
+			return ability
+		}
+	}
+	if len(sheet.Classes) == 0 { //Sheets that haven't been migrated to a class list yet
+		return CastingAbilities[classKey(sheet.Class)]
+	}
+	return ""
+}
+
+//Gives the key of a class name in the class tables
+func classKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+//Derive computes the modifiers, bonuses and other derived values of a sheet, and flags the stored values that disagree with them
+func Derive(sheet pages.Sheet) pages.Derived {
+	derived := pages.Derived{
+		Modifiers:        map[string]int{},
+		ProficiencyBonus: ProficiencyBonus(sheet.Level),
+		Saves:            []pages.Bonus{},
+		Skills:           []pages.Bonus{},
+		Mismatches:       []pages.Mismatch{},
+	}
+	for ability, score := range Scores(sheet) {
+		derived.Modifiers[ability] = Modifier(score)
+	}
+	for _, ability := range pages.AbilityNames {
+		save := pages.Bonus{Name: ability, Ability: ability, Proficient: contains(sheet.Saves, ability)}
+		save.Bonus = derived.Modifiers[ability] + proficiency(derived.ProficiencyBonus, save.Proficient, false)
+		derived.Saves = append(derived.Saves, save)
+	}
+	perception := 0
+	for _, skill := range pages.Skills {
+		bonus := pages.Bonus{
+			Name:       skill,
+			Ability:    SkillAbilities[skill],
+			Proficient: contains(sheet.ProficientSkills, skill),
+			Expert:     contains(sheet.ExpertSkills, skill),
+		}
+		bonus.Bonus = derived.Modifiers[bonus.Ability] + proficiency(derived.ProficiencyBonus, bonus.Proficient, bonus.Expert)
+		if skill == "Perception" {
+			perception = bonus.Bonus
+		}
+		derived.Skills = append(derived.Skills, bonus)
+	}
+	derived.Initiative = derived.Modifiers["Dexterity"]
+	derived.PassivePerception = 10 + perception
+	derived.SpellcastingAbility = CastingAbility(sheet)
+	if derived.SpellcastingAbility != "" {
+		derived.SpellAttack = derived.ProficiencyBonus + derived.Modifiers[derived.SpellcastingAbility]
+		derived.SpellSaveDC = 8 + derived.SpellAttack
+	}
+	derived.SpellSlots = SpellSlots(sheet)
+	derived.PactSlots = PactSlots(sheet)
+	derived.ComputedAC, derived.ACSources = ArmorClass(sheet)
+	derived.AC = derived.ComputedAC
+	if sheet.AC > 0 {
+		derived.AC = sheet.AC
+		derived.ACOverridden = true
+	}
+	derived.Attacks = Attacks(sheet)
+	derived.CarriedWeight = CarriedWeight(sheet)
+	derived.CarryingCapacity = CarryingCapacity(sheet)
+	derived.Encumbrance, _ = Encumbrance(sheet)
+	derived.Attuned = Attuned(sheet)
+	derived.WealthGP = WealthGP(sheet)
+	derived.InventoryWarnings = InventoryWarnings(sheet)
+	derived.Speed = Speed(sheet)
+	derived.MaxHitPoints = MaxHitPoints(sheet)
+	derived.Effects = Effects(sheet)
+	derived.Dead = sheet.HitPoints.Dead || sheet.Exhaustion >= len(ExhaustionEffects)
+	checkStored(&derived, "proficiency", sheet.Proficiency, derived.ProficiencyBonus)
+	checkStored(&derived, "initiative", sheet.Initiative, derived.Initiative)
+	checkStored(&derived, "passivePerception", sheet.PassivePerception, derived.PassivePerception)
+	return derived
+}
+
+//Gives the part of a bonus that comes from proficiency. Expertise doubles it
+func proficiency(bonus int, proficient bool, expert bool) int {
+	if expert {
+		return bonus * 2
+	} else if proficient {
+		return bonus
+	}
+	return 0
+}
+
+//Records a mismatch if the stored value disagrees with the computed one
+func checkStored(derived *pages.Derived, field string, stored int, computed int) {
+	if stored != computed {
+		derived.Mismatches = append(derived.Mismatches, pages.Mismatch{Field: field, Stored: stored, Computed: computed})
+	}
+}
+
+//Checks if a list holds the given value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
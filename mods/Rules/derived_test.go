@@ -0,0 +1,95 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+)
+
+func TestModifier(t *testing.T) {
+	tests := []struct {
+		score int
+		want  int
+	}{
+		{1, -5}, {8, -1}, {9, -1}, {10, 0}, {11, 0}, {12, 1}, {15, 2}, {20, 5}, {30, 10},
+	}
+	for _, test := range tests {
+		if got := Modifier(test.score); got != test.want {
+			t.Errorf("Modifier(%d) = %d, want %d", test.score, got, test.want)
+		}
+	}
+}
+
+func TestProficiencyBonus(t *testing.T) {
+	tests := []struct {
+		level int
+		want  int
+	}{
+		{0, 2}, {1, 2}, {4, 2}, {5, 3}, {9, 4}, {13, 5}, {17, 6}, {20, 6},
+	}
+	for _, test := range tests {
+		if got := ProficiencyBonus(test.level); got != test.want {
+			t.Errorf("ProficiencyBonus(%d) = %d, want %d", test.level, got, test.want)
+		}
+	}
+}
+
+func TestCastingAbility(t *testing.T) {
+	tests := []struct {
+		name  string
+		sheet pages.Sheet
+		want  string
+	}{
+		{"no caster", pages.Sheet{Classes: []pages.ClassLevel{{Name: "Fighter", Level: 3}}}, ""},
+		{"first caster", pages.Sheet{Classes: []pages.ClassLevel{{Name: "Fighter", Level: 3}, {Name: " wizard", Level: 1}, {Name: "Cleric", Level: 1}}}, "Intelligence"},
+		{"picked", pages.Sheet{SpellcastingAbility: "Wisdom", Classes: []pages.ClassLevel{{Name: "Wizard", Level: 1}}}, "Wisdom"},
+		{"no class list", pages.Sheet{Class: "Bard"}, "Charisma"},
+	}
+	for _, test := range tests {
+		if got := CastingAbility(test.sheet); got != test.want {
+			t.Errorf("%s: CastingAbility gave %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	sheet := pages.Sheet{
+		Level:             5,
+		Classes:           []pages.ClassLevel{{Name: "Cleric", Level: 5, HitDie: "1d8"}},
+		Scores:            pages.Abilities{Strength: 8, Dexterity: 14, Constitution: 12, Intelligence: 10, Wisdom: 17, Charisma: 11},
+		Saves:             []string{"Wisdom", "Charisma"},
+		ProficientSkills:  []string{"Insight", "Perception"},
+		ExpertSkills:      []string{"Perception"},
+		Proficiency:       3,
+		Initiative:        1, //Should be 2
+		PassivePerception: 19,
+	}
+	derived := Derive(sheet)
+	if derived.ProficiencyBonus != 3 || derived.Modifiers["Strength"] != -1 || derived.Modifiers["Wisdom"] != 3 {
+		t.Errorf("the bonus is %d and the modifiers are %v", derived.ProficiencyBonus, derived.Modifiers)
+	}
+	bonuses := map[string]int{}
+	for _, save := range derived.Saves {
+		bonuses[save.Name+" save"] = save.Bonus
+	}
+	for _, skill := range derived.Skills {
+		bonuses[skill.Name] = skill.Bonus
+	}
+	want := map[string]int{"Wisdom save": 6, "Charisma save": 3, "Strength save": -1, "Insight": 6, "Perception": 9, "Stealth": 2, "Athletics": -1}
+	for name, bonus := range want {
+		if bonuses[name] != bonus {
+			t.Errorf("%s is %+d, want %+d", name, bonuses[name], bonus)
+		}
+	}
+	if len(derived.Saves) != 6 || len(derived.Skills) != len(pages.Skills) {
+		t.Errorf("got %d saves and %d skills", len(derived.Saves), len(derived.Skills))
+	}
+	if derived.Initiative != 2 || derived.PassivePerception != 19 {
+		t.Errorf("initiative is %d and passive perception %d", derived.Initiative, derived.PassivePerception)
+	}
+	if derived.SpellcastingAbility != "Wisdom" || derived.SpellAttack != 6 || derived.SpellSaveDC != 14 {
+		t.Errorf("casting with %q gives an attack of %+d and DC %d", derived.SpellcastingAbility, derived.SpellAttack, derived.SpellSaveDC)
+	}
+	if len(derived.Mismatches) != 1 || derived.Mismatches[0] != (pages.Mismatch{Field: "initiative", Stored: 1, Computed: 2}) {
+		t.Errorf("the mismatches are %+v", derived.Mismatches)
+	}
+}
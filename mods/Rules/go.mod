@@ -0,0 +1,7 @@
+module Rules
+
+go 1.15
+
+replace Pages => ../Pages
+
+require Pages v0.0.0-00010101000000-000000000000
@@ -0,0 +1,178 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+)
+
+//ErrDead is returned when a dead character is healed or takes damage. It has to be revived first
+var ErrDead = errors.New("the character is dead, and has to be revived first")
+
+//ErrBadAmount is returned when a hit point change has a negative amount
+var ErrBadAmount = errors.New("the amount can't be negative")
+
+//ErrNotDying is returned when a character that isn't dying makes a death saving throw
+var ErrNotDying = errors.New("only a character at 0 hit points that isn't stable makes death saves")
+
+//ErrNotDead is returned when a character that isn't dead is revived
+var ErrNotDead = errors.New("the character isn't dead")
+
+//ErrBadHPAction is returned for a hit point action we don't know
+var ErrBadHPAction = errors.New("the action must be damage, heal, temp, deathsave or revive")
+
+//HPAction is a change to the hit points of a character
+type HPAction struct {
+	Action   string `json:"action"`   //"damage", "heal", "temp", "deathsave" or "revive"
+	Amount   int    `json:"amount"`   //The damage, healing or temporary hit points. For "deathsave", the d20 roll, or 0 to let the server roll
+	Critical bool   `json:"critical"` //Damage from a critical hit, which counts as two failed death saves at 0 hit points
+}
+
+//ApplyHP applies a hit point action to the sheet. Roll is used to roll the d20 of a death save when no roll is given
+func ApplyHP(sheet *pages.Sheet, action HPAction, roll func(sides int) int) error {
+	if action.Amount < 0 {
+		return ErrBadAmount
+	}
+	switch action.Action {
+	case "damage":
+		return Damage(sheet, action.Amount, action.Critical)
+	case "heal":
+		return Heal(sheet, action.Amount)
+	case "temp":
+		GainTemp(sheet, action.Amount)
+		return nil
+	case "deathsave":
+		if action.Amount == 0 {
+			action.Amount = roll(20)
+		}
+		return DeathSave(sheet, action.Amount)
+	case "revive":
+		return Revive(sheet, action.Amount)
+	}
+	return ErrBadHPAction
+}
+
+//Damage lowers the hit points of the character. Temporary hit points are lost first. Damage that drops the character
+//to 0 hit points and still has as much left as its max hit points kills it outright. Damage taken at 0 hit points
+//counts as a failed death save, or two if it's from a critical hit
+func Damage(sheet *pages.Sheet, amount int, critical bool) error {
+	hp := &sheet.HitPoints
+	if hp.Dead {
+		return ErrDead
+	}
+	absorbed := amount
+	if absorbed > hp.Temp {
+		absorbed = hp.Temp
+	}
+	hp.Temp -= absorbed
+	amount -= absorbed
+	if amount == 0 {
+		return nil
+	}
+	if hp.Current == 0 { //Already dying
+		hp.Stable = false
+		if amount >= hp.Max {
+			hp.Dead = true
+		} else if critical {
+			failDeathSaves(hp, 2)
+		} else {
+			failDeathSaves(hp, 1)
+		}
+		return nil
+	}
+	left := amount - hp.Current
+	if left < 0 {
+		hp.Current = -left
+		return nil
+	}
+	hp.Current = 0
+	hp.DeathSaves = pages.DeathSaves{}
+	hp.Stable = false
+	if left >= hp.Max { //Massive damage
+		hp.Dead = true
+	}
+	return nil
+}
+
+//Heal raises the current hit points of the character, up to its max hit points after exhaustion. A dying character
+//regains consciousness, and its death saves are reset
+func Heal(sheet *pages.Sheet, amount int) error {
+	hp := &sheet.HitPoints
+	if hp.Dead {
+		return ErrDead
+	}
+	if amount == 0 {
+		return nil
+	}
+	hp.Current += amount
+	if hp.Current > MaxHitPoints(*sheet) {
+		hp.Current = MaxHitPoints(*sheet)
+	}
+	hp.DeathSaves = pages.DeathSaves{}
+	hp.Stable = false
+	return nil
+}
+
+//GainTemp gives the character temporary hit points. They don't stack, so the character keeps the higher amount
+func GainTemp(sheet *pages.Sheet, amount int) {
+	if amount > sheet.HitPoints.Temp {
+		sheet.HitPoints.Temp = amount
+	}
+}
+
+//DeathSave records a death saving throw with the given d20 roll. A 20 brings the character back with 1 hit point,
+//a 1 counts as two failures, three successes make the character stable and three failures kill it
+func DeathSave(sheet *pages.Sheet, roll int) error {
+	hp := &sheet.HitPoints
+	if hp.Dead {
+		return ErrDead
+	}
+	if hp.Current > 0 || hp.Stable {
+		return ErrNotDying
+	}
+	switch {
+	case roll >= 20:
+		return Heal(sheet, 1)
+	case roll <= 1:
+		failDeathSaves(hp, 2)
+	case roll < 10:
+		failDeathSaves(hp, 1)
+	default:
+		hp.DeathSaves.Successes++
+		if hp.DeathSaves.Successes >= 3 {
+			hp.DeathSaves = pages.DeathSaves{}
+			hp.Stable = true
+		}
+	}
+	return nil
+}
+
+//Revive brings a dead character back to life with the given hit points, at least 1
+func Revive(sheet *pages.Sheet, amount int) error {
+	hp := &sheet.HitPoints
+	if !hp.Dead {
+		return ErrNotDead
+	}
+	hp.Dead = false
+	hp.Current = 0
+	hp.DeathSaves = pages.DeathSaves{}
+	if amount < 1 {
+		amount = 1
+	}
+	return Heal(sheet, amount)
+}
+
+//GainMaxHP raises the max hit points, and the current ones with them
+func GainMaxHP(sheet *pages.Sheet, amount int) {
+	sheet.HitPoints.Max += amount
+	sheet.HitPoints.Current += amount
+	sheet.Health = sheet.HitPoints.Max
+}
+
+//Records failed death saves, and kills the character at three
+func failDeathSaves(hp *pages.HitPoints, amount int) {
+	hp.DeathSaves.Failures += amount
+	if hp.DeathSaves.Failures >= 3 {
+		hp.DeathSaves.Failures = 3
+		hp.Dead = true
+	}
+}
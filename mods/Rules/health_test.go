@@ -0,0 +1,93 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+)
+
+//Makes a sheet with the given current and max hit points
+func hpSheet(current int, max int) pages.Sheet {
+	return pages.Sheet{HitPoints: pages.HitPoints{Current: current, Max: max}}
+}
+
+func TestDamage(t *testing.T) {
+	sheet := hpSheet(10, 20)
+	sheet.HitPoints.Temp = 5
+	Damage(&sheet, 8, false)
+	if sheet.HitPoints.Temp != 0 || sheet.HitPoints.Current != 7 {
+		t.Errorf("temporary hit points weren't lost first: %+v", sheet.HitPoints)
+	}
+	Damage(&sheet, 10, false)
+	if sheet.HitPoints.Current != 0 || sheet.HitPoints.Dead {
+		t.Errorf("dropping to 0 gave %+v", sheet.HitPoints)
+	}
+	Damage(&sheet, 1, true)
+	if sheet.HitPoints.DeathSaves.Failures != 2 {
+		t.Errorf("a critical hit at 0 hit points gave %d failures, want 2", sheet.HitPoints.DeathSaves.Failures)
+	}
+	Damage(&sheet, 1, false)
+	if !sheet.HitPoints.Dead {
+		t.Error("three failed death saves didn't kill the character")
+	}
+	if err := Damage(&sheet, 1, false); err != ErrDead {
+		t.Errorf("damaging a dead character gave %v, want ErrDead", err)
+	}
+	massive := hpSheet(5, 10)
+	Damage(&massive, 15, false)
+	if !massive.HitPoints.Dead {
+		t.Error("massive damage didn't kill the character")
+	}
+}
+
+func TestHeal(t *testing.T) {
+	sheet := hpSheet(0, 20)
+	sheet.HitPoints.DeathSaves.Failures = 2
+	Heal(&sheet, 50)
+	if sheet.HitPoints.Current != 20 || sheet.HitPoints.DeathSaves.Failures != 0 {
+		t.Errorf("healing gave %+v", sheet.HitPoints)
+	}
+	sheet.Exhaustion = 4 //Halves the hit point maximum
+	sheet.HitPoints.Current = 1
+	Heal(&sheet, 50)
+	if sheet.HitPoints.Current != 10 {
+		t.Errorf("healing with exhaustion 4 gave %d hit points, want 10", sheet.HitPoints.Current)
+	}
+}
+
+func TestDeathSave(t *testing.T) {
+	sheet := hpSheet(0, 20)
+	for _, roll := range []int{10, 15, 12} {
+		if err := DeathSave(&sheet, roll); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !sheet.HitPoints.Stable {
+		t.Error("three successes didn't make the character stable")
+	}
+	if err := DeathSave(&sheet, 10); err != ErrNotDying {
+		t.Errorf("a death save while stable gave %v, want ErrNotDying", err)
+	}
+	natural := hpSheet(0, 20)
+	DeathSave(&natural, 20)
+	if natural.HitPoints.Current != 1 {
+		t.Errorf("a natural 20 gave %d hit points, want 1", natural.HitPoints.Current)
+	}
+}
+
+func TestApplyHP(t *testing.T) {
+	sheet := hpSheet(5, 20)
+	if err := ApplyHP(&sheet, HPAction{Action: "heal", Amount: -3}, nil); err != ErrBadAmount {
+		t.Errorf("a negative amount gave %v, want ErrBadAmount", err)
+	}
+	if err := ApplyHP(&sheet, HPAction{Action: "explode"}, nil); err != ErrBadHPAction {
+		t.Errorf("an unknown action gave %v, want ErrBadHPAction", err)
+	}
+	if err := ApplyHP(&sheet, HPAction{Action: "revive"}, nil); err != ErrNotDead {
+		t.Errorf("reviving a living character gave %v, want ErrNotDead", err)
+	}
+	ApplyHP(&sheet, HPAction{Action: "temp", Amount: 4}, nil)
+	ApplyHP(&sheet, HPAction{Action: "temp", Amount: 2}, nil)
+	if sheet.HitPoints.Temp != 4 {
+		t.Errorf("temporary hit points stacked to %d, want 4", sheet.HitPoints.Temp)
+	}
+}
@@ -0,0 +1,111 @@
+package rules
+
+import (
+	pages "Pages"
+	"fmt"
+)
+
+//MaxAttuned is the most magic items a character can be attuned to
+const MaxAttuned = 3
+
+//CoinsPerPound is how many coins weigh a pound
+const CoinsPerPound = 50
+
+//SizeCarryFactors maps each size to what its carrying capacity is multiplied by
+var SizeCarryFactors = map[string]float64{
+	"Tiny":       0.5,
+	"Small":      1,
+	"Medium":     1,
+	"Large":      2,
+	"Huge":       4,
+	"Gargantuan": 8,
+}
+
+//CarryingCapacity gives the weight in pounds the character can carry, which is 15 times its strength adjusted for its size
+func CarryingCapacity(sheet pages.Sheet) float64 {
+	return float64(sheet.Scores.Strength) * 15 * sizeFactor(sheet)
+}
+
+//CarriedWeight gives the weight in pounds the character carries, coins included. Items kept in a weightless container,
+//like a bag of holding, don't count
+func CarriedWeight(sheet pages.Sheet) float64 {
+	weight := 0.0
+	for _, item := range sheet.Inventory {
+		if !inWeightless(sheet, item) {
+			weight += item.Weight * float64(item.Amount)
+		}
+	}
+	coins := sheet.Money.CP + sheet.Money.SP + sheet.Money.EP + sheet.Money.GP + sheet.Money.PP
+	return weight + float64(coins)/CoinsPerPound
+}
+
+//Encumbrance gives how much the carried weight slows the character, and how much its speed drops.
+//Gives an empty string for a character that isn't slowed
+func Encumbrance(sheet pages.Sheet) (string, int) {
+	weight := CarriedWeight(sheet)
+	limit := float64(sheet.Scores.Strength) * sizeFactor(sheet)
+	switch {
+	case weight > CarryingCapacity(sheet):
+		return "Over carrying capacity", sheet.Speed - 5 //Can only push, drag or lift it at 5ft
+	case sheet.Encumbrance != "variant":
+		return "", 0
+	case weight > limit*10:
+		return "Heavily encumbered", 20
+	case weight > limit*5:
+		return "Encumbered", 10
+	}
+	return "", 0
+}
+
+//Attuned counts the magic items the character is attuned to
+func Attuned(sheet pages.Sheet) int {
+	count := 0
+	for _, item := range sheet.Inventory {
+		if item.Attuned {
+			count++
+		}
+	}
+	return count
+}
+
+//InventoryWarnings lists what is wrong with what the character carries, like being over its carrying capacity
+func InventoryWarnings(sheet pages.Sheet) []string {
+	warnings := []string{}
+	if state, _ := Encumbrance(sheet); state != "" {
+		warnings = append(warnings, fmt.Sprintf("%s: carrying %.1f of %.0f lb", state, CarriedWeight(sheet), CarryingCapacity(sheet)))
+	}
+	if Attuned(sheet) > MaxAttuned {
+		warnings = append(warnings, fmt.Sprintf("Attuned to %d items, but only %d are allowed", Attuned(sheet), MaxAttuned))
+	}
+	return warnings
+}
+
+//Gives what the carrying capacity of the character is multiplied by for its size. Unknown sizes count as medium
+func sizeFactor(sheet pages.Sheet) float64 {
+	if factor, ok := SizeCarryFactors[sheet.Size]; ok {
+		return factor
+	}
+	return 1
+}
+
+//Checks if an item is kept inside a weightless container, directly or through other containers
+func inWeightless(sheet pages.Sheet, item pages.Item) bool {
+	container := item.Container
+	for steps := 0; container != "" && steps < len(sheet.Inventory); steps++ { //Stops on containers that loop
+		found := false
+		for _, other := range sheet.Inventory {
+			if other.Name == container {
+				if other.Weightless {
+					return true
+				}
+				container = other.Container
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return false
+}
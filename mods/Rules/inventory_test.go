@@ -0,0 +1,83 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+)
+
+func TestCarriedWeight(t *testing.T) {
+	sheet := pages.Sheet{
+		Inventory: []pages.Item{
+			{Name: "Rope", Amount: 2, Weight: 10},
+			{Name: "Bag of holding", Amount: 1, Weight: 15, Weightless: true},
+			{Name: "Anvil", Amount: 1, Weight: 100, Container: "Bag of holding"},
+			{Name: "Pouch", Amount: 1, Weight: 1, Container: "Bag of holding"},
+			{Name: "Gem", Amount: 4, Weight: 0.5, Container: "Pouch"}, //Inside the bag through the pouch
+			{Name: "Sack", Amount: 1, Weight: 0.5, Container: "Box"},
+			{Name: "Box", Amount: 1, Weight: 2, Container: "Sack"}, //Containers that hold each other still count
+		},
+		Money: pages.Coin{GP: 75, SP: 25},
+	}
+	if weight := CarriedWeight(sheet); weight != 39.5 { //20 rope, 15 bag, 2.5 sack and box, 2 coins
+		t.Errorf("CarriedWeight gave %v, want 39.5", weight)
+	}
+}
+
+func TestCarryingCapacity(t *testing.T) {
+	tests := []struct {
+		size string
+		want float64
+	}{
+		{"Medium", 150}, {"Tiny", 75}, {"Large", 300}, {"", 150},
+	}
+	for _, test := range tests {
+		if got := CarryingCapacity(pages.Sheet{Size: test.size, Scores: pages.Abilities{Strength: 10}}); got != test.want {
+			t.Errorf("CarryingCapacity of a %q character gave %v, want %v", test.size, got, test.want)
+		}
+	}
+}
+
+func TestEncumbrance(t *testing.T) {
+	tests := []struct {
+		name    string
+		weight  float64
+		variant bool
+		state   string
+		drop    int
+	}{
+		{"light", 50, true, "", 0},
+		{"encumbered", 51, true, "Encumbered", 10},
+		{"heavily encumbered", 101, true, "Heavily encumbered", 20},
+		{"without the variant", 101, false, "", 0},
+		{"over capacity", 151, false, "Over carrying capacity", 25},
+	}
+	for _, test := range tests {
+		sheet := pages.Sheet{Speed: 30, Scores: pages.Abilities{Strength: 10}, Inventory: []pages.Item{{Name: "Load", Amount: 1, Weight: test.weight}}}
+		if test.variant {
+			sheet.Encumbrance = "variant"
+		}
+		if state, drop := Encumbrance(sheet); state != test.state || drop != test.drop {
+			t.Errorf("%s: Encumbrance gave %q and %d, want %q and %d", test.name, state, drop, test.state, test.drop)
+		}
+	}
+	slowed := pages.Sheet{Speed: 30, Encumbrance: "variant", Scores: pages.Abilities{Strength: 10}, Inventory: []pages.Item{{Amount: 1, Weight: 60}}}
+	if speed := Speed(slowed); speed != 20 {
+		t.Errorf("an encumbered character has speed %d, want 20", speed)
+	}
+}
+
+func TestInventoryWarnings(t *testing.T) {
+	sheet := pages.Sheet{Scores: pages.Abilities{Strength: 10}}
+	for i := 0; i < 4; i++ {
+		sheet.Inventory = append(sheet.Inventory, pages.Item{Name: "Ring", Amount: 1, Attuned: true})
+	}
+	warnings := InventoryWarnings(sheet)
+	if Attuned(sheet) != 4 || len(warnings) != 1 || warnings[0] != "Attuned to 4 items, but only 3 are allowed" {
+		t.Errorf("InventoryWarnings gave %q", warnings)
+	}
+	sheet.Inventory = append(sheet.Inventory[:3], pages.Item{Name: "Load", Amount: 1, Weight: 200})
+	warnings = InventoryWarnings(sheet)
+	if len(warnings) != 1 || warnings[0] != "Over carrying capacity: carrying 200.0 of 150 lb" {
+		t.Errorf("InventoryWarnings gave %q", warnings)
+	}
+}
@@ -0,0 +1,164 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//MaxLevel is the highest level a character can reach
+const MaxLevel = 20
+
+//ErrMaxLevel is returned when a character at the highest level tries to level up
+var ErrMaxLevel = errors.New("the character is already at the highest level")
+
+//ErrNotEnoughXP is returned when a character doesn't have the exp needed for the next level
+var ErrNotEnoughXP = errors.New("the character doesn't have enough exp for the next level")
+
+//ErrBadHPChoice is returned when the way of deciding gained hit points isn't one we know
+var ErrBadHPChoice = errors.New("hit points must be gained by average, roll or manual")
+
+//ErrBadClass is returned when the class to level up in can't be used
+var ErrBadClass = errors.New("choose one of the character's classes, or a new class with a known hit die")
+
+//XPThresholds holds the exp needed to reach each level. The exp for level n is at index n-1
+var XPThresholds = []int{0, 300, 900, 2700, 6500, 14000, 23000, 34000, 48000, 64000, 85000, 100000, 120000, 140000, 165000, 195000, 225000, 265000, 305000, 355000}
+
+//HPChoice is how the hit points gained on a level-up are decided
+type HPChoice struct {
+	Method string `json:"method"` //"average" takes the rounded up average of the hit die, "roll" rolls it, and "manual" uses Value
+	Value  int    `json:"value"`  //The hit die result the player rolled themselves, used by "manual"
+	Class  string `json:"class"`  //The class to gain the level in. Empty picks the first class, and a class the character doesn't have yet multiclasses into it
+	HitDie string `json:"hitDie"` //The hit die of a new class. Can be left out for the classes in the handbook
+}
+
+//LevelForXP gives the level a character with the given exp has reached
+func LevelForXP(xp int) int {
+	level := 1
+	for i, threshold := range XPThresholds {
+		if xp >= threshold {
+			level = i + 1
+		}
+	}
+	return level
+}
+
+//NextLevelXP gives the exp needed to reach the level after the given one. Gives 0 at the highest level
+func NextLevelXP(level int) int {
+	if level < 1 {
+		level = 1
+	}
+	if level >= MaxLevel {
+		return 0
+	}
+	return XPThresholds[level]
+}
+
+//HitDieSides gives the amount of sides of a hit die like "1d10". Gives 0 if the die can't be read
+func HitDieSides(die string) int {
+	parts := strings.SplitN(strings.ToLower(die), "d", 2)
+	if len(parts) != 2 {
+		return 0
+	}
+	sides, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	return sides
+}
+
+//AverageHitDie gives the fixed hit points a die gives instead of rolling, which is its average rounded up
+func AverageHitDie(sides int) int {
+	return sides/2 + 1
+}
+
+//CanLevelUp checks if the character is allowed to reach the next level
+func CanLevelUp(sheet pages.Sheet) error {
+	if sheet.Level >= MaxLevel {
+		return ErrMaxLevel
+	}
+	if !sheet.Milestone && sheet.CurrentExpirience < XPThresholds[sheet.Level] {
+		return ErrNotEnoughXP
+	}
+	return nil
+}
+
+//LevelUp moves the character to the next level in the chosen class. It updates the class levels, hit dice, spell slots,
+//resources, proficiency bonus, exp needed and health, and records the level-up on the sheet. Roll is used to roll the hit die
+//when the choice is "roll"
+func LevelUp(sheet *pages.Sheet, choice HPChoice, roll func(sides int) int, now time.Time) (pages.LevelRecord, error) {
+	record := pages.LevelRecord{}
+	sheet.Migrate()
+	if err := CanLevelUp(*sheet); err != nil {
+		return record, err
+	}
+	class, err := levelClass(sheet, choice)
+	if err != nil {
+		return record, err
+	}
+	sides := HitDieSides(class.HitDie)
+	switch choice.Method {
+	case "average":
+		record.HPGained = AverageHitDie(sides)
+	case "roll":
+		record.HPGained = roll(sides)
+	case "manual":
+		if choice.Value < 1 || choice.Value > sides {
+			return record, ErrBadHPChoice
+		}
+		record.HPGained = choice.Value
+	default:
+		return record, ErrBadHPChoice
+	}
+	record.HPGained += Modifier(sheet.Scores.Constitution)
+	if record.HPGained < 1 { //A level always gives at least one hit point
+		record.HPGained = 1
+	}
+	if class.Level == 0 { //Multiclassing into a new class
+		sheet.Classes = append(sheet.Classes, class)
+	}
+	for i := range sheet.Classes {
+		if sheet.Classes[i].Name == class.Name {
+			sheet.Classes[i].Level++
+		}
+	}
+	sheet.SyncClasses()
+	UpdateSlots(sheet)
+	UpdateResources(sheet)
+	sheet.Proficiency = ProficiencyBonus(sheet.Level)
+	if !sheet.Milestone {
+		sheet.NextExpirience = NextLevelXP(sheet.Level)
+	}
+	GainMaxHP(sheet, record.HPGained)
+	record.Class = class.Name
+	record.Level = sheet.Level
+	record.Date = now
+	record.Experience = sheet.CurrentExpirience
+	record.HPMethod = choice.Method
+	record.Milestone = sheet.Milestone
+	sheet.LevelHistory = append(sheet.LevelHistory, record)
+	return record, nil
+}
+
+//Finds the class a level-up is for. A class the character doesn't have yet is given at level 0
+func levelClass(sheet *pages.Sheet, choice HPChoice) (pages.ClassLevel, error) {
+	name := strings.TrimSpace(choice.Class)
+	for _, class := range sheet.Classes {
+		if name == "" || classKey(class.Name) == classKey(name) {
+			return class, nil
+		}
+	}
+	if name == "" { //No classes to pick from
+		return pages.ClassLevel{}, ErrBadClass
+	}
+	class := pages.ClassLevel{Name: name, HitDie: choice.HitDie}
+	if class.HitDie == "" {
+		class.HitDie = ClassHitDice[classKey(name)]
+	}
+	if HitDieSides(class.HitDie) == 0 {
+		return class, ErrBadClass
+	}
+	return class, nil
+}
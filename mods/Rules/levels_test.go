@@ -0,0 +1,140 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+	"time"
+)
+
+//Makes a level 3 fighter with 14 constitution and the given exp
+func levelSheet(xp int) pages.Sheet {
+	return pages.Sheet{
+		Classes:           []pages.ClassLevel{{Name: "Fighter", Level: 3, HitDie: "1d10"}},
+		Scores:            pages.Abilities{Constitution: 14},
+		HitPoints:         pages.HitPoints{Max: 28, Current: 20},
+		CurrentExpirience: xp,
+	}
+}
+
+func TestLevelForXP(t *testing.T) {
+	tests := []struct {
+		xp   int
+		want int
+	}{
+		{0, 1}, {299, 1}, {300, 2}, {2700, 4}, {6499, 4}, {355000, 20}, {1000000, 20},
+	}
+	for _, test := range tests {
+		if got := LevelForXP(test.xp); got != test.want {
+			t.Errorf("LevelForXP(%d) = %d, want %d", test.xp, got, test.want)
+		}
+	}
+	if NextLevelXP(1) != 300 || NextLevelXP(4) != 6500 || NextLevelXP(20) != 0 || NextLevelXP(0) != 300 {
+		t.Error("NextLevelXP gave the wrong exp")
+	}
+}
+
+func TestHitDieSides(t *testing.T) {
+	tests := []struct {
+		die  string
+		want int
+	}{
+		{"1d10", 10}, {"1D6", 6}, {"d12", 12}, {"10", 0}, {"1dx", 0}, {"", 0},
+	}
+	for _, test := range tests {
+		if got := HitDieSides(test.die); got != test.want {
+			t.Errorf("HitDieSides(%q) = %d, want %d", test.die, got, test.want)
+		}
+	}
+}
+
+func TestLevelUp(t *testing.T) {
+	now := time.Now()
+	sheet := levelSheet(2700)
+	record, err := LevelUp(&sheet, HPChoice{Method: "average"}, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.HPGained != 8 || record.Level != 4 || record.Class != "Fighter" || !record.Date.Equal(now) { //6 for a d10 and 2 for constitution
+		t.Errorf("the level-up was recorded as %+v", record)
+	}
+	if sheet.Level != 4 || sheet.Classes[0].Level != 4 || sheet.NextExpirience != 6500 || sheet.Proficiency != 2 {
+		t.Errorf("the sheet is level %d with %d exp needed and a bonus of %d", sheet.Level, sheet.NextExpirience, sheet.Proficiency)
+	}
+	if sheet.HitPoints.Max != 36 || sheet.HitPoints.Current != 28 || len(sheet.LevelHistory) != 1 {
+		t.Errorf("the hit points are %+v with %d levels recorded", sheet.HitPoints, len(sheet.LevelHistory))
+	}
+	rolled := levelSheet(2700)
+	if record, _ := LevelUp(&rolled, HPChoice{Method: "roll"}, func(sides int) int { return sides }, now); record.HPGained != 12 {
+		t.Errorf("rolling a 10 gained %d hit points, want 12", record.HPGained)
+	}
+	frail := levelSheet(2700)
+	frail.Scores.Constitution = 3
+	if record, _ := LevelUp(&frail, HPChoice{Method: "manual", Value: 1}, nil, now); record.HPGained != 1 {
+		t.Errorf("a level gained %d hit points, want at least 1", record.HPGained)
+	}
+	milestone := levelSheet(0)
+	milestone.Milestone = true
+	if _, err := LevelUp(&milestone, HPChoice{Method: "average"}, nil, now); err != nil {
+		t.Errorf("a milestone level-up without exp gave %v", err)
+	}
+}
+
+func TestLevelUpRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		xp     int
+		choice HPChoice
+		want   error
+	}{
+		{"not enough exp", 2699, HPChoice{Method: "average"}, ErrNotEnoughXP},
+		{"unknown method", 2700, HPChoice{Method: "guess"}, ErrBadHPChoice},
+		{"manual above the die", 2700, HPChoice{Method: "manual", Value: 11}, ErrBadHPChoice},
+		{"manual below 1", 2700, HPChoice{Method: "manual", Value: 0}, ErrBadHPChoice},
+	}
+	for _, test := range tests {
+		sheet := levelSheet(test.xp)
+		if _, err := LevelUp(&sheet, test.choice, nil, time.Now()); err != test.want {
+			t.Errorf("%s: LevelUp gave %v, want %v", test.name, err, test.want)
+		}
+		if sheet.Level != 3 || len(sheet.LevelHistory) != 0 {
+			t.Errorf("%s: a refused level-up changed the sheet", test.name)
+		}
+	}
+	maxed := pages.Sheet{Classes: []pages.ClassLevel{{Name: "Fighter", Level: MaxLevel, HitDie: "1d10"}}, Milestone: true}
+	if _, err := LevelUp(&maxed, HPChoice{Method: "average"}, nil, time.Now()); err != ErrMaxLevel {
+		t.Errorf("leveling past the highest level gave %v, want ErrMaxLevel", err)
+	}
+}
+
+func TestLevelUpMulticlass(t *testing.T) {
+	sheet := levelSheet(2700)
+	record, err := LevelUp(&sheet, HPChoice{Method: "average", Class: "wizard"}, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Class != "wizard" || record.HPGained != 6 { //4 for a d6 and 2 for constitution
+		t.Errorf("the level-up was recorded as %+v", record)
+	}
+	if len(sheet.Classes) != 2 || sheet.Classes[1] != (pages.ClassLevel{Name: "wizard", Level: 1, HitDie: "1d6"}) {
+		t.Errorf("the classes are %+v", sheet.Classes)
+	}
+	if sheet.Level != 4 || sheet.Class != "Fighter 3 / wizard 1" || len(sheet.SpellSlots) != 1 || sheet.SpellSlots[0].Max != 2 {
+		t.Errorf("the sheet is %q level %d with slots %+v", sheet.Class, sheet.Level, sheet.SpellSlots)
+	}
+	sheet.CurrentExpirience = 6500
+	if _, err := LevelUp(&sheet, HPChoice{Method: "average", Class: "Wizard"}, nil, time.Now()); err != nil || sheet.Classes[1].Level != 2 {
+		t.Errorf("leveling the new class again gave %v and %+v", err, sheet.Classes)
+	}
+	custom := levelSheet(2700)
+	if _, err := LevelUp(&custom, HPChoice{Method: "average", Class: "Blood Hunter", HitDie: "1d10"}, nil, time.Now()); err != nil {
+		t.Errorf("multiclassing with a given hit die gave %v", err)
+	}
+	unknown := levelSheet(2700)
+	if _, err := LevelUp(&unknown, HPChoice{Method: "average", Class: "Blood Hunter"}, nil, time.Now()); err != ErrBadClass {
+		t.Errorf("multiclassing into a class without a hit die gave %v, want ErrBadClass", err)
+	}
+	classless := pages.Sheet{CurrentExpirience: 300}
+	if _, err := LevelUp(&classless, HPChoice{Method: "average"}, nil, time.Now()); err != ErrBadClass {
+		t.Errorf("leveling a sheet without classes gave %v, want ErrBadClass", err)
+	}
+}
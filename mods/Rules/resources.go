@@ -0,0 +1,141 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//ErrBadFormula is returned for a resource formula that can't be read
+var ErrBadFormula = errors.New("the formula can't be read")
+
+//AbilityShorts maps the short names used in formulas to the abilities
+var AbilityShorts = map[string]string{
+	"str": "Strength",
+	"dex": "Dexterity",
+	"con": "Constitution",
+	"int": "Intelligence",
+	"wis": "Wisdom",
+	"cha": "Charisma",
+}
+
+//EvalFormula computes the max uses of a resource from a formula like "level", "monk", "1+cha" or "paladin*5".
+//A formula adds and subtracts terms. A term is a whole number, "level" for the character level, "proficiency"
+//for the proficiency bonus, a class name for the levels in that class, or a short ability name for its modifier,
+//and can be multiplied or divided (rounding down) by whole numbers. The result is at least 1
+func EvalFormula(sheet pages.Sheet, formula string) (int, error) {
+	formula = strings.ToLower(strings.Join(strings.Fields(formula), ""))
+	formula = strings.ReplaceAll(formula, "-", "+-")
+	total := 0
+	for _, term := range strings.Split(formula, "+") {
+		if term == "" {
+			continue
+		}
+		value, err := evalTerm(sheet, term)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrBadFormula, term)
+		}
+		total += value
+	}
+	if total < 1 {
+		total = 1
+	}
+	return total, nil
+}
+
+//Computes one term of a formula, like "-cha", "monk" or "paladin*5"
+func evalTerm(sheet pages.Sheet, term string) (int, error) {
+	sign := 1
+	if strings.HasPrefix(term, "-") {
+		sign = -1
+		term = term[1:]
+	}
+	parts := strings.Split(strings.ReplaceAll(term, "/", "*"), "*") //Keeps the empty operands of dangling operators, like in "monk*"
+	ops := strings.Map(func(r rune) rune {
+		if r == '*' || r == '/' {
+			return r
+		}
+		return -1
+	}, term)
+	if len(parts) != len(ops)+1 {
+		return 0, ErrBadFormula
+	}
+	for _, part := range parts {
+		if part == "" {
+			return 0, ErrBadFormula
+		}
+	}
+	value, err := evalValue(sheet, parts[0])
+	if err != nil {
+		return 0, err
+	}
+	for i, op := range []rune(ops) {
+		num, err := strconv.Atoi(parts[i+1])
+		if err != nil || (op == '/' && num == 0) {
+			return 0, ErrBadFormula
+		}
+		if op == '*' {
+			value *= num
+		} else {
+			value /= num
+		}
+	}
+	return sign * value, nil
+}
+
+//Computes a single value in a formula
+func evalValue(sheet pages.Sheet, name string) (int, error) {
+	if num, err := strconv.Atoi(name); err == nil {
+		return num, nil
+	}
+	switch name {
+	case "level":
+		return sheet.Level, nil
+	case "proficiency":
+		return ProficiencyBonus(sheet.Level), nil
+	}
+	if ability, ok := AbilityShorts[name]; ok {
+		return Modifier(Scores(sheet)[ability]), nil
+	}
+	if class := findClass(&sheet, name); class != nil {
+		return class.Level, nil
+	}
+	if _, ok := ClassHitDice[name]; ok { //A class the character has no levels in
+		return 0, nil
+	}
+	return 0, ErrBadFormula
+}
+
+//UpdateResources sets the max uses of the resources that have a formula. When the max goes up, so do the current uses.
+//Gives the errors of formulas that can't be read, keyed by their path in the sheet
+func UpdateResources(sheet *pages.Sheet) pages.FieldErrors {
+	errs := pages.FieldErrors{}
+	for i := range sheet.Resources {
+		resource := &sheet.Resources[i]
+		if strings.TrimSpace(resource.Formula) == "" {
+			continue
+		}
+		max, err := EvalFormula(*sheet, resource.Formula)
+		if err != nil {
+			errs.Add(fmt.Sprintf("resources.%d", i), err.Error())
+			continue
+		}
+		if max > resource.Max {
+			resource.Current += max - resource.Max
+		}
+		resource.Max = max
+		resource.Current = clamp(resource.Current, 0, resource.Max)
+	}
+	return errs
+}
+
+//RestoreResources gives back all uses of the resources that recharge in one of the given ways
+func RestoreResources(sheet *pages.Sheet, recharges ...string) {
+	for i := range sheet.Resources {
+		if contains(recharges, sheet.Resources[i].Recharge) {
+			sheet.Resources[i].Current = sheet.Resources[i].Max
+		}
+	}
+}
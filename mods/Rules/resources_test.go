@@ -0,0 +1,101 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+	"testing"
+)
+
+func TestEvalFormula(t *testing.T) {
+	sheet := pages.Sheet{
+		Level:   7,
+		Classes: []pages.ClassLevel{{Name: "Monk", Level: 5, HitDie: "1d8"}, {Name: "Paladin", Level: 2, HitDie: "1d10"}},
+		Scores:  pages.Abilities{Strength: 10, Dexterity: 16, Constitution: 12, Intelligence: 8, Wisdom: 14, Charisma: 14},
+	}
+	tests := []struct {
+		formula string
+		want    int
+		ok      bool
+	}{
+		{"level", 7, true},
+		{"monk", 5, true},
+		{"Monk", 5, true},
+		{"1+cha", 3, true},
+		{"paladin*5", 10, true},
+		{"monk / 2", 2, true},
+		{"monk*3/2", 7, true},
+		{"proficiency", 3, true},
+		{"wizard", 1, true}, //No levels in the class, but the result is at least 1
+		{"int", 1, true},
+		{"10-int", 11, true},
+		{"4", 4, true},
+		{"", 1, true},
+		{"monk*", 0, false},
+		{"*5", 0, false},
+		{"cha**2", 0, false},
+		{"monk/", 0, false},
+		{"monk/0", 0, false},
+		{"monk*cha", 0, false},
+		{"1--cha", 0, false},
+		{"mana", 0, false},
+	}
+	for _, test := range tests {
+		got, err := EvalFormula(sheet, test.formula)
+		if test.ok && (err != nil || got != test.want) {
+			t.Errorf("EvalFormula(%q) = %d, %v, want %d", test.formula, got, err, test.want)
+		}
+		if !test.ok && !errors.Is(err, ErrBadFormula) {
+			t.Errorf("EvalFormula(%q) = %d, %v, want ErrBadFormula", test.formula, got, err)
+		}
+	}
+}
+
+func TestUpdateResources(t *testing.T) {
+	sheet := pages.Sheet{
+		Level:   5,
+		Classes: []pages.ClassLevel{{Name: "Monk", Level: 5, HitDie: "1d8"}},
+		Resources: []pages.Resource{
+			{Name: "Ki", Current: 2, Max: 4, Recharge: "short", Formula: "monk"},
+			{Name: "Broken", Current: 1, Max: 1, Recharge: "long", Formula: "monk*"},
+		},
+	}
+	errs := UpdateResources(&sheet)
+	if sheet.Resources[0].Max != 5 || sheet.Resources[0].Current != 3 {
+		t.Errorf("Ki is %+v, want 3 of 5", sheet.Resources[0])
+	}
+	if _, ok := errs["resources.1"]; !ok || len(errs) != 1 {
+		t.Errorf("UpdateResources gave errors %v, want one for resources.1", errs)
+	}
+}
+
+func TestUpdateResourcesLowersMax(t *testing.T) {
+	sheet := pages.Sheet{
+		Level:     3,
+		Classes:   []pages.ClassLevel{{Name: "Monk", Level: 3, HitDie: "1d8"}},
+		Resources: []pages.Resource{{Name: "Ki", Current: 5, Max: 5, Recharge: "short", Formula: "monk"}, {Name: "Luck", Current: 1, Max: 3, Recharge: "long"}},
+	}
+	UpdateResources(&sheet)
+	if sheet.Resources[0].Max != 3 || sheet.Resources[0].Current != 3 {
+		t.Errorf("Ki is %+v, want 3 of 3", sheet.Resources[0])
+	}
+	if sheet.Resources[1].Max != 3 || sheet.Resources[1].Current != 1 {
+		t.Errorf("a resource without a formula changed to %+v", sheet.Resources[1])
+	}
+}
+
+func TestRestoreResources(t *testing.T) {
+	sheet := pages.Sheet{Resources: []pages.Resource{
+		{Name: "Action Surge", Max: 1, Recharge: "short"},
+		{Name: "Rage", Max: 3, Recharge: "long"},
+		{Name: "Wand", Max: 7, Current: 2, Recharge: "dawn"},
+		{Name: "Wish", Max: 1, Recharge: "none"},
+	}}
+	RestoreResources(&sheet, "short", "dawn")
+	got := []int{}
+	for _, resource := range sheet.Resources {
+		got = append(got, resource.Current)
+	}
+	if got[0] != 1 || got[1] != 0 || got[2] != 7 || got[3] != 0 {
+		t.Errorf("the uses are %v, want [1 0 7 0]", got)
+	}
+}
@@ -0,0 +1,167 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//ErrNoHitDice is returned when a short rest spends more hit dice than a class has left
+var ErrNoHitDice = errors.New("the class doesn't have that many hit dice left")
+
+//ErrBadRest is returned for a rest we don't know, hit dice that don't match the class, or a long rest at 0 hit points
+var ErrBadRest = errors.New("the rest must be short, long or dawn, and spend dice of the character's classes with rolls that fit the die. A long rest needs at least 1 hit point")
+
+//Rest is a short or long rest taken by a character, or the dawn of a new day
+type Rest struct {
+	Type string      `json:"type"` //"short", "long" or "dawn"
+	Dice []DiceSpend `json:"dice"` //Hit dice spent on a short rest
+}
+
+//DiceSpend is a number of hit dice of one class spent on a short rest
+type DiceSpend struct {
+	Class string `json:"class"`
+	Count int    `json:"count"`
+	Rolls []int  `json:"rolls"` //The rolls of the player, one for each die. Left out to let the server roll
+}
+
+//TakeRest applies a short or long rest, or a dawn, to the sheet, and records it in the sheet's history
+func TakeRest(sheet *pages.Sheet, rest Rest, roll func(sides int) int, now time.Time) (pages.Event, error) {
+	switch rest.Type {
+	case "short":
+		return ShortRest(sheet, rest.Dice, roll, now)
+	case "long":
+		return LongRest(sheet, now)
+	case "dawn":
+		RestoreResources(sheet, "dawn")
+		return addEvent(sheet, "dawn", "Dawn. Regained the features that recharge at dawn", now), nil
+	}
+	return pages.Event{}, ErrBadRest
+}
+
+//ShortRest spends the given hit dice, healing the character by each roll plus its constitution modifier,
+//and regains the warlock pact slots and the features that recharge on a short rest
+func ShortRest(sheet *pages.Sheet, dice []DiceSpend, roll func(sides int) int, now time.Time) (pages.Event, error) {
+	if sheet.HitPoints.Dead {
+		return pages.Event{}, ErrDead
+	}
+	healed := 0
+	spent := []string{}
+	for _, spend := range dice {
+		if spend.Count == 0 {
+			continue
+		}
+		class := findClass(sheet, spend.Class)
+		if class == nil || spend.Count < 0 || (spend.Rolls != nil && len(spend.Rolls) != spend.Count) {
+			return pages.Event{}, ErrBadRest
+		}
+		if class.HitDiceSpent+spend.Count > class.Level {
+			return pages.Event{}, ErrNoHitDice
+		}
+		sides := HitDieSides(class.HitDie)
+		for i := 0; i < spend.Count; i++ {
+			result := 0
+			if spend.Rolls != nil {
+				result = spend.Rolls[i]
+			} else {
+				result = roll(sides)
+			}
+			if result < 1 || result > sides {
+				return pages.Event{}, ErrBadRest
+			}
+			result += Modifier(sheet.Scores.Constitution)
+			if result > 0 {
+				healed += result
+			}
+		}
+		class.HitDiceSpent += spend.Count
+		spent = append(spent, fmt.Sprintf("%d%s", spend.Count, class.HitDie[1:]))
+	}
+	before := sheet.HitPoints.Current
+	if err := Heal(sheet, healed); err != nil {
+		return pages.Event{}, err
+	}
+	sheet.PactSlots.Used = 0
+	RestoreResources(sheet, "short")
+	description := "Short rest"
+	if len(spent) > 0 {
+		description += fmt.Sprintf(". Spent %s hit dice and regained %d hit points", strings.Join(spent, " + "), sheet.HitPoints.Current-before)
+	}
+	return addEvent(sheet, "shortRest", description, now), nil
+}
+
+//LongRest restores the hit points, half of the hit dice, the spell and pact slots and the limited-use features
+//of the character, and lowers its exhaustion by one. A long rest runs through the night, so dawn features recharge too.
+//A character has to start it with at least 1 hit point to benefit from it
+func LongRest(sheet *pages.Sheet, now time.Time) (pages.Event, error) {
+	hp := &sheet.HitPoints
+	if hp.Dead {
+		return pages.Event{}, ErrDead
+	}
+	if hp.Current == 0 {
+		return pages.Event{}, ErrBadRest
+	}
+	if sheet.Exhaustion > 0 {
+		sheet.Exhaustion--
+	}
+	hp.Current = MaxHitPoints(*sheet)
+	hp.Temp = 0
+	hp.DeathSaves = pages.DeathSaves{}
+	hp.Stable = false
+	regained := regainHitDice(sheet)
+	for i := range sheet.SpellSlots {
+		sheet.SpellSlots[i].Used = 0
+	}
+	sheet.PactSlots.Used = 0
+	RestoreResources(sheet, "short", "long", "dawn")
+	description := fmt.Sprintf("Long rest. Regained all hit points and %d hit dice", regained)
+	return addEvent(sheet, "longRest", description, now), nil
+}
+
+//Regains spent hit dice, up to half of the character's level but at least one, starting with the largest dice.
+//Gives the amount regained
+func regainHitDice(sheet *pages.Sheet) int {
+	left := sheet.Level / 2
+	if left < 1 {
+		left = 1
+	}
+	order := make([]int, len(sheet.Classes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return HitDieSides(sheet.Classes[order[a]].HitDie) > HitDieSides(sheet.Classes[order[b]].HitDie)
+	})
+	regained := 0
+	for _, i := range order {
+		class := &sheet.Classes[i]
+		amount := class.HitDiceSpent
+		if amount > left {
+			amount = left
+		}
+		class.HitDiceSpent -= amount
+		left -= amount
+		regained += amount
+	}
+	return regained
+}
+
+//Finds one of the character's classes by name
+func findClass(sheet *pages.Sheet, name string) *pages.ClassLevel {
+	for i := range sheet.Classes {
+		if classKey(sheet.Classes[i].Name) == classKey(name) {
+			return &sheet.Classes[i]
+		}
+	}
+	return nil
+}
+
+//Records an event in the sheet's history
+func addEvent(sheet *pages.Sheet, kind string, description string, now time.Time) pages.Event {
+	event := pages.Event{Date: now, Type: kind, Description: description}
+	sheet.History = append(sheet.History, event)
+	return event
+}
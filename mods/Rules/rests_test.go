@@ -0,0 +1,135 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+	"time"
+)
+
+//Makes a level 5 fighter, level 2 wizard with 14 constitution, some hit dice and features spent, and hurt
+func restSheet() pages.Sheet {
+	return pages.Sheet{
+		Level:     7,
+		Scores:    pages.Abilities{Constitution: 14},
+		HitPoints: pages.HitPoints{Max: 50, Current: 10, Temp: 3},
+		Classes: []pages.ClassLevel{
+			{Name: "Wizard", Level: 2, HitDie: "1d6", HitDiceSpent: 2},
+			{Name: "Fighter", Level: 5, HitDie: "1d10", HitDiceSpent: 3},
+		},
+		SpellSlots: []pages.SpellSlot{{Level: 1, Max: 3, Used: 2}},
+		PactSlots:  pages.SpellSlot{Level: 1, Max: 1, Used: 1},
+		Resources: []pages.Resource{
+			{Name: "Action Surge", Max: 1, Recharge: "short"},
+			{Name: "Arcane Recovery", Max: 1, Recharge: "long"},
+			{Name: "Wand", Max: 7, Current: 2, Recharge: "dawn"},
+		},
+		Exhaustion: 2,
+	}
+}
+
+func TestShortRest(t *testing.T) {
+	now := time.Now()
+	sheet := restSheet()
+	event, err := ShortRest(&sheet, []DiceSpend{{Class: "fighter", Count: 2, Rolls: []int{4, 7}}}, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sheet.HitPoints.Current != 25 { //4+2 and 7+2 on top of 10
+		t.Errorf("current hit points are %d, want 25", sheet.HitPoints.Current)
+	}
+	if sheet.Classes[1].HitDiceSpent != 5 || sheet.PactSlots.Used != 0 || sheet.SpellSlots[0].Used != 2 {
+		t.Errorf("the rest gave %+v", sheet)
+	}
+	if sheet.Resources[0].Current != 1 || sheet.Resources[1].Current != 0 || sheet.Resources[2].Current != 2 {
+		t.Errorf("the features are %+v, want only the short rest ones back", sheet.Resources)
+	}
+	if event.Type != "shortRest" || len(sheet.History) != 1 || !sheet.History[0].Date.Equal(now) {
+		t.Errorf("the rest was recorded as %+v", sheet.History)
+	}
+	rolled := restSheet()
+	if _, err := ShortRest(&rolled, []DiceSpend{{Class: "Wizard", Count: 0}, {Class: "Fighter", Count: 1}}, func(sides int) int { return sides }, now); err != nil {
+		t.Fatal(err)
+	}
+	if rolled.HitPoints.Current != 22 { //A rolled 10 plus 2
+		t.Errorf("current hit points are %d after rolling, want 22", rolled.HitPoints.Current)
+	}
+}
+
+func TestShortRestRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		dice []DiceSpend
+		want error
+	}{
+		{"unknown class", []DiceSpend{{Class: "Rogue", Count: 1, Rolls: []int{3}}}, ErrBadRest},
+		{"negative count", []DiceSpend{{Class: "Fighter", Count: -1}}, ErrBadRest},
+		{"missing rolls", []DiceSpend{{Class: "Fighter", Count: 2, Rolls: []int{3}}}, ErrBadRest},
+		{"roll above the die", []DiceSpend{{Class: "Wizard", Count: 0}, {Class: "Fighter", Count: 1, Rolls: []int{11}}}, ErrBadRest},
+		{"spent dice", []DiceSpend{{Class: "Wizard", Count: 1, Rolls: []int{3}}}, ErrNoHitDice},
+		{"too many dice", []DiceSpend{{Class: "Fighter", Count: 3, Rolls: []int{1, 1, 1}}}, ErrNoHitDice},
+	}
+	for _, test := range tests {
+		sheet := restSheet()
+		if _, err := ShortRest(&sheet, test.dice, nil, time.Now()); err != test.want {
+			t.Errorf("%s: ShortRest gave %v, want %v", test.name, err, test.want)
+		}
+	}
+	dead := restSheet()
+	dead.HitPoints.Dead = true
+	if _, err := ShortRest(&dead, nil, nil, time.Now()); err != ErrDead {
+		t.Errorf("a dead character's short rest gave %v, want ErrDead", err)
+	}
+}
+
+func TestLongRest(t *testing.T) {
+	sheet := restSheet()
+	sheet.HitPoints.DeathSaves.Failures = 1
+	if _, err := LongRest(&sheet, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	hp := sheet.HitPoints
+	if hp.Current != 50 || hp.Temp != 0 || hp.DeathSaves.Failures != 0 || sheet.Exhaustion != 1 {
+		t.Errorf("the hit points are %+v with exhaustion %d", hp, sheet.Exhaustion)
+	}
+	if sheet.Classes[0].HitDiceSpent != 2 || sheet.Classes[1].HitDiceSpent != 0 { //3 dice back, largest first
+		t.Errorf("the classes are %+v", sheet.Classes)
+	}
+	if sheet.SpellSlots[0].Used != 0 || sheet.PactSlots.Used != 0 {
+		t.Error("the slots weren't regained")
+	}
+	for _, resource := range sheet.Resources {
+		if resource.Current != resource.Max {
+			t.Errorf("%s wasn't regained", resource.Name)
+		}
+	}
+}
+
+func TestLongRestRejected(t *testing.T) {
+	down := restSheet()
+	down.HitPoints.Current = 0
+	down.HitPoints.Stable = true
+	if _, err := LongRest(&down, time.Now()); err != ErrBadRest {
+		t.Errorf("a long rest at 0 hit points gave %v, want ErrBadRest", err)
+	}
+	if down.HitPoints.Current != 0 || down.Exhaustion != 2 || len(down.History) != 0 {
+		t.Error("a refused long rest changed the sheet")
+	}
+	dead := restSheet()
+	dead.HitPoints.Current, dead.HitPoints.Dead = 0, true
+	if _, err := LongRest(&dead, time.Now()); err != ErrDead {
+		t.Errorf("a dead character's long rest gave %v, want ErrDead", err)
+	}
+}
+
+func TestTakeRest(t *testing.T) {
+	sheet := restSheet()
+	if _, err := TakeRest(&sheet, Rest{Type: "dawn"}, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if sheet.Resources[2].Current != 7 || sheet.Resources[0].Current != 0 || sheet.HitPoints.Current != 10 {
+		t.Errorf("dawn gave %+v", sheet)
+	}
+	if _, err := TakeRest(&sheet, Rest{Type: "nap"}, nil, time.Now()); err != ErrBadRest {
+		t.Errorf("an unknown rest gave %v, want ErrBadRest", err)
+	}
+}
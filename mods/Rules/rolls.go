@@ -0,0 +1,71 @@
+package rules
+
+import (
+	pages "Pages"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//MaxRollLog is the most rolls kept in the roll log of a sheet. Older rolls are dropped
+const MaxRollLog = 100
+
+//ErrBadCheck is returned when a roll is asked for something the sheet doesn't have
+var ErrBadCheck = errors.New(`checks must be initiative, or skill:, save:, ability:, attack: or damage: followed by a skill, ability or attack of the sheet`)
+
+//CheckRoll gives the dice notation and label of a roll for the sheet, using its computed modifiers. Check is "initiative",
+//"skill:<skill>", "save:<ability>", "ability:<ability>", "attack:<attack>" or "damage:<attack>"
+func CheckRoll(sheet pages.Sheet, check string) (string, string, error) {
+	derived := Derive(sheet)
+	kind, name := check, ""
+	if i := strings.Index(check, ":"); i >= 0 {
+		kind, name = check[:i], check[i+1:]
+	}
+	switch kind {
+	case "initiative":
+		return d20(derived.Initiative), "Initiative", nil
+	case "skill", "save":
+		bonuses := derived.Skills
+		label := name + " check"
+		if kind == "save" {
+			bonuses = derived.Saves
+			label = name + " save"
+		}
+		for _, bonus := range bonuses {
+			if bonus.Name == name {
+				return d20(bonus.Bonus), label, nil
+			}
+		}
+	case "ability":
+		if modifier, ok := derived.Modifiers[name]; ok {
+			return d20(modifier), name + " check", nil
+		}
+	case "attack", "damage":
+		for _, attack := range derived.Attacks {
+			if attack.Name != name {
+				continue
+			}
+			if kind == "attack" {
+				return d20(attack.ToHit), name + " attack", nil
+			}
+			return attack.Damage, name + " damage", nil
+		}
+	}
+	return "", "", ErrBadCheck
+}
+
+//LogRoll adds a roll to the roll log of the sheet, dropping the oldest rolls past MaxRollLog
+func LogRoll(sheet *pages.Sheet, record pages.RollRecord) {
+	sheet.Rolls = append(sheet.Rolls, record)
+	if len(sheet.Rolls) > MaxRollLog {
+		sheet.Rolls = sheet.Rolls[len(sheet.Rolls)-MaxRollLog:]
+	}
+}
+
+//Writes a d20 roll with a bonus, like "1d20+5"
+func d20(bonus int) string {
+	if bonus == 0 {
+		return "1d20"
+	}
+	return fmt.Sprintf("1d20%+d", bonus)
+}
@@ -0,0 +1,153 @@
+package rules
+
+import (
+	pages "Pages"
+)
+
+//FullCasters holds the classes, in lower case, that gain spell slots at every level
+var FullCasters = []string{"bard", "cleric", "druid", "sorcerer", "wizard"}
+
+//HalfCasters holds the classes, in lower case, that gain spell slots at half the rate of full casters
+var HalfCasters = []string{"paladin", "ranger"}
+
+//ThirdCasters holds the subclasses, in lower case, that gain spell slots at a third of the rate of full casters
+var ThirdCasters = []string{"eldritch knight", "arcane trickster"}
+
+//SlotTable holds the spell slots of a full caster, or a multiclassed caster, of each caster level.
+//The slots of caster level n are at index n-1, and hold the amount of slots of spell levels 1 to 9
+var SlotTable = [][9]int{
+	{2, 0, 0, 0, 0, 0, 0, 0, 0},
+	{3, 0, 0, 0, 0, 0, 0, 0, 0},
+	{4, 2, 0, 0, 0, 0, 0, 0, 0},
+	{4, 3, 0, 0, 0, 0, 0, 0, 0},
+	{4, 3, 2, 0, 0, 0, 0, 0, 0},
+	{4, 3, 3, 0, 0, 0, 0, 0, 0},
+	{4, 3, 3, 1, 0, 0, 0, 0, 0},
+	{4, 3, 3, 2, 0, 0, 0, 0, 0},
+	{4, 3, 3, 3, 1, 0, 0, 0, 0},
+	{4, 3, 3, 3, 2, 0, 0, 0, 0},
+	{4, 3, 3, 3, 2, 1, 0, 0, 0},
+	{4, 3, 3, 3, 2, 1, 0, 0, 0},
+	{4, 3, 3, 3, 2, 1, 1, 0, 0},
+	{4, 3, 3, 3, 2, 1, 1, 0, 0},
+	{4, 3, 3, 3, 2, 1, 1, 1, 0},
+	{4, 3, 3, 3, 2, 1, 1, 1, 0},
+	{4, 3, 3, 3, 2, 1, 1, 1, 1},
+	{4, 3, 3, 3, 3, 1, 1, 1, 1},
+	{4, 3, 3, 3, 3, 2, 1, 1, 1},
+	{4, 3, 3, 3, 3, 2, 2, 1, 1},
+}
+
+//PactTable holds the pact magic slots of each warlock level, as the amount of slots and their spell level.
+//The slots of warlock level n are at index n-1
+var PactTable = [][2]int{
+	{1, 1}, {2, 1}, {2, 2}, {2, 2}, {2, 3}, {2, 3}, {2, 4}, {2, 4}, {2, 5}, {2, 5},
+	{3, 5}, {3, 5}, {3, 5}, {3, 5}, {3, 5}, {3, 5}, {4, 5}, {4, 5}, {4, 5}, {4, 5},
+}
+
+//CasterLevel gives the level used to look up the character's spell slots in SlotTable. Half and third casters
+//count part of their class level. A single class rounds up, while multiclassed casters round down for each class,
+//except for artificers who always round up. Warlock levels don't count, since pact magic has its own slots
+func CasterLevel(sheet pages.Sheet) int {
+	level := 0
+	casters := 0
+	for _, class := range sheet.Classes {
+		if casterRate(class) != 0 {
+			casters++
+		}
+	}
+	for _, class := range sheet.Classes {
+		rate := casterRate(class)
+		switch {
+		case rate == 0:
+			continue
+		case rate == 1:
+			level += class.Level
+		case classKey(class.Name) == "artificer":
+			level += (class.Level + 1) / 2
+		case casters == 1 && class.Level >= rate: //A single half or third caster gets no slots before its spellcasting feature
+			level += (class.Level + rate - 1) / rate
+		case casters > 1:
+			level += class.Level / rate
+		}
+	}
+	if level > len(SlotTable) {
+		level = len(SlotTable)
+	}
+	return level
+}
+
+//Gives how many class levels count as one caster level for a class. 0 if the class doesn't gain spell slots
+func casterRate(class pages.ClassLevel) int {
+	switch name := classKey(class.Name); {
+	case contains(FullCasters, name):
+		return 1
+	case contains(HalfCasters, name), name == "artificer":
+		return 2
+	case contains(ThirdCasters, classKey(class.Subclass)):
+		return 3
+	}
+	return 0
+}
+
+//SpellSlots gives the spell slots the character has of each spell level, from level 1 up to the highest level it has slots of
+func SpellSlots(sheet pages.Sheet) []pages.SpellSlot {
+	slots := []pages.SpellSlot{}
+	level := CasterLevel(sheet)
+	if level == 0 {
+		return slots
+	}
+	for i, max := range SlotTable[level-1] {
+		if max > 0 {
+			slots = append(slots, pages.SpellSlot{Level: i + 1, Max: max})
+		}
+	}
+	return slots
+}
+
+//PactSlots gives the pact magic slots of the character's warlock levels. Gives an empty slot if it has none
+func PactSlots(sheet pages.Sheet) pages.SpellSlot {
+	for _, class := range sheet.Classes {
+		if classKey(class.Name) == "warlock" && class.Level > 0 {
+			level := class.Level
+			if level > len(PactTable) {
+				level = len(PactTable)
+			}
+			return pages.SpellSlot{Level: PactTable[level-1][1], Max: PactTable[level-1][0]}
+		}
+	}
+	return pages.SpellSlot{}
+}
+
+//UpdateSlots sets the spell and pact slots of the sheet to the ones its class levels give, keeping the used slots
+//of each spell level. Gives false if the slots were already right
+func UpdateSlots(sheet *pages.Sheet) bool {
+	used := map[int]int{}
+	for _, slot := range sheet.SpellSlots {
+		used[slot.Level] = slot.Used
+	}
+	slots := SpellSlots(*sheet)
+	for i := range slots {
+		slots[i].Used = clamp(used[slots[i].Level], 0, slots[i].Max)
+	}
+	pact := PactSlots(*sheet)
+	pact.Used = clamp(sheet.PactSlots.Used, 0, pact.Max)
+	changed := pact != sheet.PactSlots || len(slots) != len(sheet.SpellSlots)
+	for i := 0; !changed && i < len(slots); i++ {
+		changed = slots[i] != sheet.SpellSlots[i]
+	}
+	sheet.SpellSlots = slots
+	sheet.PactSlots = pact
+	return changed
+}
+
+//Keeps a number within the given range
+func clamp(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -0,0 +1,48 @@
+package rules
+
+import (
+	pages "Pages"
+	"testing"
+)
+
+func TestCasterLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		classes []pages.ClassLevel
+		want    int
+	}{
+		{"wizard", []pages.ClassLevel{{Name: "Wizard", Level: 5}}, 5},
+		{"single paladin rounds up", []pages.ClassLevel{{Name: "Paladin", Level: 5}}, 3},
+		{"paladin 1 has no slots", []pages.ClassLevel{{Name: "Paladin", Level: 1}}, 0},
+		{"eldritch knight", []pages.ClassLevel{{Name: "Fighter", Subclass: "Eldritch Knight", Level: 7}}, 3},
+		{"multiclass rounds down", []pages.ClassLevel{{Name: "Wizard", Level: 3}, {Name: "Ranger", Level: 3}}, 4},
+		{"warlock doesn't count", []pages.ClassLevel{{Name: "Warlock", Level: 5}, {Name: "Cleric", Level: 1}}, 1},
+		{"fighter", []pages.ClassLevel{{Name: "Fighter", Level: 10}}, 0},
+		{"capped at 20", []pages.ClassLevel{{Name: "Wizard", Level: 20}, {Name: "Cleric", Level: 20}}, 20},
+	}
+	for _, test := range tests {
+		if got := CasterLevel(pages.Sheet{Classes: test.classes}); got != test.want {
+			t.Errorf("%s: CasterLevel = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestUpdateSlots(t *testing.T) {
+	sheet := pages.Sheet{
+		Classes:    []pages.ClassLevel{{Name: "Wizard", Level: 3}, {Name: "Warlock", Level: 2}},
+		SpellSlots: []pages.SpellSlot{{Level: 1, Max: 2, Used: 2}, {Level: 2, Max: 0, Used: 5}},
+	}
+	if !UpdateSlots(&sheet) {
+		t.Error("UpdateSlots didn't report the change")
+	}
+	want := []pages.SpellSlot{{Level: 1, Max: 4, Used: 2}, {Level: 2, Max: 2, Used: 2}}
+	if len(sheet.SpellSlots) != len(want) || sheet.SpellSlots[0] != want[0] || sheet.SpellSlots[1] != want[1] {
+		t.Errorf("spell slots are %+v, want %+v", sheet.SpellSlots, want)
+	}
+	if sheet.PactSlots != (pages.SpellSlot{Level: 1, Max: 2}) {
+		t.Errorf("pact slots are %+v", sheet.PactSlots)
+	}
+	if UpdateSlots(&sheet) {
+		t.Error("UpdateSlots reported a change on slots that were already right")
+	}
+}
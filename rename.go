@@ -0,0 +1,41 @@
+package main
+
+import (
+	pages "Pages"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+//ErrBadName is returned when a sheet is renamed without a new name
+var ErrBadName = errors.New("the new name of the sheet is required")
+
+//Gives one of the user's sheets a new name. Its ID stays the same, so links to the sheet keep working
+func (s *server) renameSheet(username string, name string, newName string) (pages.Sheet, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return pages.Sheet{}, ErrBadName
+	}
+	if newName == name { //Nothing to do
+		return s.store.GetSheet(username, name)
+	}
+	return s.store.RenameSheet(username, name, newName)
+}
+
+//Handler renames a sheet from the index page, and routes back to the index
+func (s *server) renameHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	name, err := s.sheetName(username, r.FormValue("sheet"))
+	if err == nil {
+		_, err = s.renameSheet(username, name, r.FormValue("name"))
+	}
+	if err != nil { //Load fail page if we fail to rename the sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	http.Redirect(w, r, "/index/", 303)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	pages "Pages"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+)
+
+//Version that stands for the sheet as it is now when comparing revisions
+const currentVersion = -1
+
+//Gives the fields that differ between two revisions of a sheet. A to of currentVersion compares with the sheet as it is now
+func (s *server) diffRevisions(username string, name string, from int, to int) ([]pages.Change, error) {
+	older, err := s.store.GetRevision(username, name, from)
+	if err != nil {
+		return nil, err
+	}
+	if to == currentVersion {
+		sheet, err := s.store.GetSheet(username, name)
+		if err != nil {
+			return nil, err
+		}
+		return pages.Diff(*older.Sheet, sheet), nil
+	}
+	newer, err := s.store.GetRevision(username, name, to)
+	if err != nil {
+		return nil, err
+	}
+	return pages.Diff(*older.Sheet, *newer.Sheet), nil
+}
+
+//Reads the versions to compare from a request. A missing to compares with the current sheet
+func diffVersions(r *http.Request) (int, int, error) {
+	from, err := strconv.Atoi(r.FormValue("from"))
+	if err != nil {
+		return 0, 0, err
+	}
+	to := currentVersion
+	if r.FormValue("to") != "" {
+		to, err = strconv.Atoi(r.FormValue("to"))
+	}
+	return from, to, err
+}
+
+//Handler loads the revisions page of a sheet. With from and to set, it also shows the diff between those versions
+func (s *server) revisionsHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Routes back to index if accessed without being logged in yet
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	sheet, err := s.findSheet(username, r.FormValue("sheet"))
+	if err != nil { //Loads error page if we failed to load the character sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	page := pages.RevisionsPage{ID: sheet.ID, Name: sheet.Name}
+	status := http.StatusOK
+	if r.FormValue("from") != "" {
+		if page.From, page.To, err = diffVersions(r); err != nil {
+			page.Error = "Pick the versions to compare"
+			s.loadRevisionsPage(w, username, http.StatusBadRequest, page)
+			return
+		}
+		changes, err := s.diffRevisions(username, page.Name, page.From, page.To)
+		if err != nil {
+			status = errorStatus(err)
+			page.Error = "Could not compare the versions: " + err.Error()
+		}
+		page.Changes = changes
+	}
+	s.loadRevisionsPage(w, username, status, page)
+}
+
+//Handler restores a revision of a sheet from the revisions page, and routes back to the sheet
+func (s *server) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	sheet, err := s.findSheet(username, r.FormValue("sheet"))
+	if err != nil { //Loads error page if we failed to load the character sheet
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	page := pages.RevisionsPage{ID: sheet.ID, Name: sheet.Name}
+	version, err := strconv.Atoi(r.FormValue("version"))
+	if err != nil {
+		page.Error = "Pick a version to restore"
+		s.loadRevisionsPage(w, username, http.StatusBadRequest, page)
+		return
+	}
+	if _, err = s.store.RestoreRevision(username, sheet.Name, version); err != nil {
+		page.Error = "Could not restore the version: " + err.Error()
+		s.loadRevisionsPage(w, username, errorStatus(err), page)
+		return
+	}
+	http.Redirect(w, r, "/sheet/?sheet="+url.QueryEscape(sheet.ID), 303)
+}
+
+//Loads the revisions page, filling in the list of revisions of its sheet
+func (s *server) loadRevisionsPage(w http.ResponseWriter, username string, status int, page pages.RevisionsPage) {
+	revisions, err := s.store.ListRevisions(username, page.Name)
+	if err != nil { //Loads error page if we failed to load the revisions
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	page.Revisions = revisions
+	pageData, err := json.Marshal(page)
+	if err != nil {
+		panic(err)
+	}
+	t, _ := template.ParseFiles("./templates/revisions.html")
+	w.WriteHeader(status)
+	t.Execute(w, string(pageData))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	dice "Dice"
+	pages "Pages"
+	rules "Rules"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+//ErrNoSheet is returned when a check is rolled without a sheet to take the modifiers from
+var ErrNoSheet = errors.New("checks need a sheet to roll for")
+
+//What to roll. Either the notation, or a check like "skill:Stealth" that takes its modifier from the sheet
+type rollRequest struct {
+	Notation string `json:"notation"` //Like "2d6+3" or "4d6kh3"
+	Check    string `json:"check"`    //"initiative", "skill:<skill>", "save:<ability>", "ability:<ability>", "attack:<attack>" or "damage:<attack>"
+	Mode     string `json:"mode"`     //"advantage", "disadvantage" or empty
+	Label    string `json:"label"`    //What the roll is for. Checks are labeled for you
+}
+
+//A finished roll with what it was for
+type rollResponse struct {
+	Label string `json:"label"`
+	dice.Result
+}
+
+//Rolls the dice of a request, and logs the roll on the given sheet. A roll without a sheet isn't logged anywhere
+func (s *server) roll(username string, name string, request rollRequest) (rollResponse, error) {
+	response := rollResponse{Label: request.Label}
+	if name == "" {
+		if request.Check != "" {
+			return response, ErrNoSheet
+		}
+		var err error
+		response.Result, err = s.dice.Roll(request.Notation, request.Mode)
+		return response, err
+	}
+	_, err := s.store.ModifySheet(username, name, func(sheet *pages.Sheet) (err error) {
+		notation := request.Notation
+		if request.Check != "" {
+			if notation, response.Label, err = rules.CheckRoll(*sheet, request.Check); err != nil {
+				return err
+			}
+		}
+		if response.Result, err = s.dice.Roll(notation, request.Mode); err != nil {
+			return err
+		}
+		rules.LogRoll(sheet, pages.RollRecord{
+			Date:     time.Now(),
+			Label:    response.Label,
+			Notation: response.Notation,
+			Mode:     response.Mode,
+			Total:    response.Total,
+			Text:     response.Text,
+		})
+		return nil
+	})
+	return response, err
+}
+
+//Handler rolls dice, for one of the user's sheets if it's given, and responds with the roll.
+//Expects a json body like {"sheet":"<name>","check":"skill:Stealth","mode":"advantage"} or {"notation":"4d6kh3"}
+func (s *server) rollHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" {
+		writeMessage(w, http.StatusUnauthorized, "You need to be logged in")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMessage(w, http.StatusMethodNotAllowed, "Use POST to roll")
+		return
+	}
+	var body struct { //Help struct that holds the decoded request
+		Sheet string `json:"sheet"`
+		rollRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Could not read the roll: "+err.Error())
+		return
+	}
+	name := ""
+	var err error
+	if body.Sheet != "" { //Rolls without a sheet aren't logged anywhere
+		name, err = s.sheetName(username, body.Sheet)
+	}
+	response := rollResponse{}
+	if err == nil {
+		response, err = s.roll(username, name, body.rollRequest)
+	}
+	if err != nil {
+		writeMessage(w, errorStatus(err), err.Error())
+	} else {
+		writeJSON(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	pages "Pages"
+	rules "Rules"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+//Helper struct for parsing feats and allies in a submitted character sheet
+type featOrAlly struct {
+	Name        string //The name of the given object
+	Description string //Description of the object
+}
+
+//Fills a sheet belonging to the given user with the values submitted in the sheet form.
+//Also gives the fields that couldn't be read, keyed by their json path in the sheet
+func parseSheetForm(r *http.Request, username string) (pages.Sheet, pages.FieldErrors) {
+	sheet := pages.Sheet{} //Fill a sheet object with all the relevant values
+	errs := pages.FieldErrors{}
+	r.ParseForm()
+	scores := pages.Abilities{
+		Strength:     formInt(r.Form, "strength", "scores.strength", true, errs),
+		Dexterity:    formInt(r.Form, "dexterity", "scores.dexterity", true, errs),
+		Constitution: formInt(r.Form, "constitution", "scores.constitution", true, errs),
+		Intelligence: formInt(r.Form, "intelligence", "scores.intelligence", true, errs),
+		Wisdom:       formInt(r.Form, "wisdom", "scores.wisdom", true, errs),
+		Charisma:     formInt(r.Form, "charisma", "scores.charisma", true, errs),
+	}
+	money := pages.Coin{
+		CP: formInt(r.Form, "cp", "money.cp", true, errs),
+		SP: formInt(r.Form, "sp", "money.sp", true, errs),
+		EP: formInt(r.Form, "ep", "money.ep", true, errs),
+		GP: formInt(r.Form, "gp", "money.gp", true, errs),
+		PP: formInt(r.Form, "pp", "money.pp", true, errs),
+	}
+	inventory := parseItems(r.Form, errs)
+	feats := []pages.Feat{}
+	for _, feat := range parseFeatsAndAllies(r.Form, "feat", "feats", errs) {
+		feats = append(feats, pages.Feat{Name: feat.Name, Description: feat.Description})
+	}
+	allies := []pages.Ally{}
+	for _, ally := range parseFeatsAndAllies(r.Form, "ally", "allies", errs) {
+		allies = append(allies, pages.Ally{Name: ally.Name, Description: ally.Description})
+	}
+	spells := parseSpells(r.Form, errs)
+	resources := parseResources(r.Form, errs)
+	attacks := parseAttacks(r.Form, errs)
+	sheet.Owner = username
+	sheet.Name = strings.TrimSpace(r.Form.Get("name"))
+	sheet.CharacterName = r.Form.Get("characterName")
+	sheet.Age = formInt(r.Form, "age", "age", false, errs)
+	sheet.Weight = r.Form.Get("weight")
+	sheet.Height = r.Form.Get("height")
+	sheet.Size = r.Form.Get("size")
+	sheet.Gender = r.Form.Get("gender")
+	sheet.EyeColor = r.Form.Get("eyeColor")
+	sheet.Skin = r.Form.Get("skin")
+	sheet.Race = r.Form.Get("race")
+	sheet.SpellcastingAbility = r.Form.Get("spellcastingAbility")
+	sheet.Classes = parseClasses(r.Form, errs)
+	sheet.Allignment = r.Form.Get("allignment")
+	sheet.Background = r.Form.Get("background")
+	sheet.Milestone = r.Form.Get("milestone") == "true"
+	sheet.CurrentExpirience = formInt(r.Form, "currentExpirience", "currentExpirience", true, errs)
+	sheet.NextExpirience = formInt(r.Form, "nextExpirience", "nextExpirience", true, errs)
+	sheet.Proficiency = formInt(r.Form, "proficiency", "proficiency", true, errs)
+	sheet.Scores = scores
+	sheet.Saves = r.Form["saves"]
+	sheet.ProficientSkills = r.Form["proficientSkills"]
+	sheet.ExpertSkills = r.Form["expertSkills"]
+	sheet.Languages = r.Form["languages"]
+	sheet.Tools = r.Form["tools"]
+	sheet.Vehicles = r.Form["vehicles"]
+	sheet.Weapons = r.Form["weapons"]
+	sheet.Armor = r.Form["armor"]
+	sheet.Inventory = inventory
+	sheet.Encumbrance = r.Form.Get("encumbrance")
+	sheet.AC = formInt(r.Form, "ac", "ac", false, errs)
+	sheet.Initiative = formInt(r.Form, "initiative", "initiative", true, errs)
+	sheet.Speed = formInt(r.Form, "speed", "speed", true, errs)
+	sheet.Ideals = r.Form.Get("ideals")
+	sheet.Bonds = r.Form.Get("bonds")
+	sheet.Flaw = r.Form.Get("flaw")
+	sheet.Feats = feats
+	sheet.Money = money
+	sheet.PassivePerception = formInt(r.Form, "passivePerception", "passivePerception", true, errs)
+	sheet.Backstory = r.Form.Get("backstory")
+	sheet.Allies = allies
+	sheet.Health = formInt(r.Form, "health", "health", true, errs)
+	sheet.Spells = spells
+	sheet.Resources = resources
+	sheet.Attacks = attacks
+	sheet.Migrate() //Fills in the summary fields and the hit points
+	rules.UpdateSlots(&sheet)
+	for path, message := range rules.UpdateResources(&sheet) {
+		errs.Add(path, message)
+	}
+	for i := range sheet.Resources { //Resources start out with all their uses. Edits keep the stored uses instead
+		sheet.Resources[i].Current = sheet.Resources[i].Max
+	}
+	for path, message := range sheet.Validate() { //Values that were read fine still have to follow the rules
+		errs.Add(path, message)
+	}
+	return sheet, errs
+}
+
+//Carries the values only the server changes, like the level history, over from the stored sheet to an edited one
+func keepTracked(stored pages.Sheet, edited pages.Sheet) pages.Sheet {
+	edited.LevelHistory = stored.LevelHistory
+	edited.History = stored.History
+	edited.Ledger = stored.Ledger
+	edited.Rolls = stored.Rolls
+	edited.Exhaustion = stored.Exhaustion
+	edited.Conditions = stored.Conditions
+	edited.Deleted = stored.Deleted
+	hp := stored.HitPoints //Only the max hit points are edited, the rest changes during play
+	hp.Current += edited.Health - hp.Max
+	if hp.Current < 0 {
+		hp.Current = 0
+	}
+	hp.Max = edited.Health
+	edited.HitPoints = hp
+	for i := range edited.Resources {
+		for _, resource := range stored.Resources {
+			if resource.Name == edited.Resources[i].Name && resource.Current < edited.Resources[i].Max {
+				edited.Resources[i].Current = resource.Current
+			}
+		}
+	}
+	for i := range edited.Classes {
+		for _, class := range stored.Classes {
+			if class.Name == edited.Classes[i].Name && class.HitDiceSpent <= edited.Classes[i].Level {
+				edited.Classes[i].HitDiceSpent = class.HitDiceSpent
+			}
+		}
+	}
+	return edited
+}
+
+//Reads a whole number from the form. Records an error under the json path if it isn't a number, or if it's required and missing
+func formInt(form url.Values, field string, path string, required bool, errs pages.FieldErrors) int {
+	value := strings.TrimSpace(form.Get(field))
+	if value == "" {
+		if required {
+			errs.Add(path, "Is required")
+		}
+		return 0
+	}
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		errs.Add(path, fmt.Sprintf("%q is not a whole number", value))
+	}
+	return num
+}
+
+//Gets the value of a repeated form field in the given row. Rows missing the field give an empty string
+func rowValue(form url.Values, field string, row int) string {
+	if row < len(form[field]) {
+		return strings.TrimSpace(form[field][row])
+	}
+	return ""
+}
+
+//Reads a number from a row field that can be left empty, which gives 0
+func rowInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+//Counts the rows of a list in the form, which is the length of its longest repeated field
+func rowCount(form url.Values, fields ...string) int {
+	count := 0
+	for _, field := range fields {
+		if len(form[field]) > count {
+			count = len(form[field])
+		}
+	}
+	return count
+}
+
+//Takes the feat or ally rows from a submitted sheet, and parses them into structs to be put into an array.
+//Each row is sent as the repeated fields <prefix>Name and <prefix>Description. Bad rows are recorded under <path>.<row>
+func parseFeatsAndAllies(form url.Values, prefix string, path string, errs pages.FieldErrors) []featOrAlly {
+	featsOrAllies := []featOrAlly{} //Array we will be returning
+	for i := 0; i < rowCount(form, prefix+"Name", prefix+"Description"); i++ {
+		feOAl := featOrAlly{
+			Name:        rowValue(form, prefix+"Name", i),
+			Description: rowValue(form, prefix+"Description", i),
+		}
+		if feOAl.Name == "" && feOAl.Description == "" { //Skip rows left empty
+			continue
+		}
+		if feOAl.Name == "" {
+			errs.Add(fmt.Sprintf("%s.%d", path, i), "Needs a name")
+			continue
+		}
+		featsOrAllies = append(featsOrAllies, feOAl)
+	}
+	return featsOrAllies
+}
+
+//Takes the inventory rows from a submitted sheet, and parses them into an inventory array.
+//Each row is sent as the repeated fields itemAmount, itemName, itemDescription, itemWeight, itemValue, itemContainer, the flags
+//itemEquipped, itemAttuned and itemWeightless, and the armor fields itemArmor, itemArmorClass and itemACBonus. Bad rows are recorded under inventory.<row>
+func parseItems(form url.Values, errs pages.FieldErrors) []pages.Item {
+	inventory := []pages.Item{} //Array we will be returning
+	for i := 0; i < rowCount(form, "itemAmount", "itemName", "itemDescription", "itemWeight", "itemValue", "itemContainer", "itemArmorClass", "itemACBonus"); i++ {
+		amount := rowValue(form, "itemAmount", i)
+		weight := rowValue(form, "itemWeight", i)
+		value := rowValue(form, "itemValue", i)
+		armorClass := rowValue(form, "itemArmorClass", i)
+		bonus := rowValue(form, "itemACBonus", i)
+		item := pages.Item{
+			Name:        rowValue(form, "itemName", i),
+			Description: rowValue(form, "itemDescription", i),
+			Container:   rowValue(form, "itemContainer", i),
+			Equipped:    rowValue(form, "itemEquipped", i) == "true",
+			Attuned:     rowValue(form, "itemAttuned", i) == "true",
+			Weightless:  rowValue(form, "itemWeightless", i) == "true",
+			Armor:       rowValue(form, "itemArmor", i),
+		}
+		if amount == "" && item.Name == "" && item.Description == "" && weight == "" && value == "" && item.Container == "" && armorClass == "" && bonus == "" { //Skip rows left empty
+			continue
+		}
+		if item.Name == "" {
+			errs.Add(fmt.Sprintf("inventory.%d", i), "Needs a name")
+			continue
+		}
+		num, err := strconv.Atoi(amount)
+		if err != nil || num < 0 {
+			errs.Add(fmt.Sprintf("inventory.%d", i), fmt.Sprintf("Amount %q is not a whole number", amount))
+			continue
+		}
+		item.Amount = num
+		if weight != "" {
+			if item.Weight, err = strconv.ParseFloat(weight, 64); err != nil {
+				errs.Add(fmt.Sprintf("inventory.%d", i), fmt.Sprintf("Weight %q is not a number", weight))
+				continue
+			}
+		}
+		if item.Value, err = rowInt(value); err != nil {
+			errs.Add(fmt.Sprintf("inventory.%d", i), fmt.Sprintf("Value %q is not a whole number", value))
+			continue
+		}
+		if item.ArmorClass, err = rowInt(armorClass); err != nil {
+			errs.Add(fmt.Sprintf("inventory.%d", i), fmt.Sprintf("AC %q is not a whole number", armorClass))
+			continue
+		}
+		if item.ACBonus, err = rowInt(bonus); err != nil {
+			errs.Add(fmt.Sprintf("inventory.%d", i), fmt.Sprintf("AC bonus %q is not a whole number", bonus))
+			continue
+		}
+		inventory = append(inventory, item)
+	}
+	return inventory
+}
+
+//Takes the spell rows from a submitted sheet, and parses them into a spell array.
+//Each row is sent as the repeated fields spellName, spellLevel, spellDescription, spellPrepared, spellRitual and spellConcentration. Bad rows are recorded under spells.<row>
+func parseSpells(form url.Values, errs pages.FieldErrors) []pages.Spell {
+	spells := []pages.Spell{} //Array we're returning
+	for i := 0; i < rowCount(form, "spellName", "spellLevel", "spellDescription"); i++ {
+		level := rowValue(form, "spellLevel", i)
+		spell := pages.Spell{
+			Name:          rowValue(form, "spellName", i),
+			Description:   rowValue(form, "spellDescription", i),
+			Prepared:      rowValue(form, "spellPrepared", i) == "true",
+			Ritual:        rowValue(form, "spellRitual", i) == "true",
+			Concentration: rowValue(form, "spellConcentration", i) == "true",
+		}
+		if level == "" && spell.Name == "" && spell.Description == "" { //Skip rows left empty
+			continue
+		}
+		if spell.Name == "" {
+			errs.Add(fmt.Sprintf("spells.%d", i), "Needs a name")
+			continue
+		}
+		num, err := strconv.Atoi(level)
+		if err != nil || num < 0 || num > 9 {
+			errs.Add(fmt.Sprintf("spells.%d", i), fmt.Sprintf("Level %q is not a number from 0 to 9", level))
+			continue
+		}
+		spell.Level = num
+		spells = append(spells, spell)
+	}
+	return spells
+}
+
+//Takes the class rows from a submitted sheet, and parses them into a class list.
+//Each row is sent as the repeated fields className, classSubclass, classLevel and classHitDie. Bad rows are recorded under classes.<row>
+func parseClasses(form url.Values, errs pages.FieldErrors) []pages.ClassLevel {
+	classes := []pages.ClassLevel{} //Array we're returning
+	for i := 0; i < rowCount(form, "className", "classSubclass", "classLevel", "classHitDie"); i++ {
+		level := rowValue(form, "classLevel", i)
+		class := pages.ClassLevel{
+			Name:     rowValue(form, "className", i),
+			Subclass: rowValue(form, "classSubclass", i),
+			HitDie:   rowValue(form, "classHitDie", i),
+		}
+		if level == "" && class.Name == "" && class.Subclass == "" { //Skip rows left empty. The hit die always has a value
+			continue
+		}
+		if class.Name == "" {
+			errs.Add(fmt.Sprintf("classes.%d", i), "Needs a name")
+			continue
+		}
+		num, err := strconv.Atoi(level)
+		if err != nil || num < 1 || num > 20 {
+			errs.Add(fmt.Sprintf("classes.%d", i), fmt.Sprintf("Level %q is not a number from 1 to 20", level))
+			continue
+		}
+		class.Level = num
+		classes = append(classes, class)
+	}
+	return classes
+}
+
+//Takes the resource rows from a submitted sheet, and parses them into a resource list. Each row is sent as the
+//repeated fields resourceName, resourceMax, resourceRecharge and resourceFormula. Bad rows are recorded under resources.<row>
+func parseResources(form url.Values, errs pages.FieldErrors) []pages.Resource {
+	resources := []pages.Resource{} //Array we're returning
+	for i := 0; i < rowCount(form, "resourceName", "resourceMax", "resourceRecharge", "resourceFormula"); i++ {
+		max := rowValue(form, "resourceMax", i)
+		resource := pages.Resource{
+			Name:     rowValue(form, "resourceName", i),
+			Recharge: rowValue(form, "resourceRecharge", i),
+			Formula:  rowValue(form, "resourceFormula", i),
+		}
+		if max == "" && resource.Name == "" && resource.Formula == "" { //Skip rows left empty. The recharge always has a value
+			continue
+		}
+		if resource.Name == "" {
+			errs.Add(fmt.Sprintf("resources.%d", i), "Needs a name")
+			continue
+		}
+		if resource.Formula == "" {
+			num, err := strconv.Atoi(max)
+			if err != nil || num < 0 {
+				errs.Add(fmt.Sprintf("resources.%d", i), fmt.Sprintf("Max uses %q is not a whole number", max))
+				continue
+			}
+			resource.Max = num
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+//Takes the attack rows from a submitted sheet, and parses them into an attack list. Each row is sent as the repeated fields
+//attackName, attackAbility, attackProficient, attackDamage, attackDamageType, attackRange and attackMagic. Bad rows are recorded under attacks.<row>
+func parseAttacks(form url.Values, errs pages.FieldErrors) []pages.Attack {
+	attacks := []pages.Attack{} //Array we're returning
+	for i := 0; i < rowCount(form, "attackName", "attackDamage", "attackRange", "attackMagic"); i++ {
+		magic := rowValue(form, "attackMagic", i)
+		attack := pages.Attack{
+			Name:       rowValue(form, "attackName", i),
+			Ability:    rowValue(form, "attackAbility", i),
+			Proficient: rowValue(form, "attackProficient", i) == "true",
+			Damage:     rowValue(form, "attackDamage", i),
+			DamageType: rowValue(form, "attackDamageType", i),
+			Range:      rowValue(form, "attackRange", i),
+		}
+		if magic == "" && attack.Name == "" && attack.Damage == "" && attack.Range == "" { //Skip rows left empty. The selects always have a value
+			continue
+		}
+		if attack.Name == "" {
+			errs.Add(fmt.Sprintf("attacks.%d", i), "Needs a name")
+			continue
+		}
+		if magic != "" {
+			num, err := strconv.Atoi(magic)
+			if err != nil {
+				errs.Add(fmt.Sprintf("attacks.%d", i), fmt.Sprintf("Magic bonus %q is not a whole number", magic))
+				continue
+			}
+			attack.MagicBonus = num
+		}
+		attacks = append(attacks, attack)
+	}
+	return attacks
+}
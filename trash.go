@@ -0,0 +1,151 @@
+package main
+
+import (
+	db "DB"
+	pages "Pages"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+//How many days sheets stay in the trash when $TRASH_DAYS isn't set
+const defaultTrashDays = 30
+
+//How often the trash is checked for sheets to purge
+const purgeInterval = time.Hour
+
+//Reads how many days sheets stay in the trash from $TRASH_DAYS. 0 keeps them until they are purged by hand
+func trashDays() (int, error) {
+	value := os.Getenv("TRASH_DAYS")
+	if value == "" {
+		return defaultTrashDays, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("$TRASH_DAYS: %q is not a number of days", value)
+	}
+	return days, nil
+}
+
+//Purges the sheets that have been in the trash longer than the retention period, right away and then every purgeInterval,
+//until stop is closed
+func (s *server) purgeTrash(stop <-chan struct{}) {
+	if s.trashDays == 0 {
+		return
+	}
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		purged, err := s.store.PurgeTrash(time.Now().AddDate(0, 0, -s.trashDays))
+		if err != nil {
+			log.Println("Purging the trash:", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d sheets from the trash\n", purged)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//Gives the sheets in a user's trash, with when each of them will be purged
+func (s *server) trashedSheets(username string) ([]pages.TrashedSheet, error) {
+	trash, err := s.store.ListTrash(username)
+	if err != nil {
+		return trash, err
+	}
+	if s.trashDays > 0 {
+		for i := range trash {
+			trash[i].PurgeAt = trash[i].Deleted.AddDate(0, 0, s.trashDays)
+		}
+	}
+	return trash, nil
+}
+
+//Gives the name of the sheet in the user's trash a request refers to, by its ID or by its name like findSheet
+func (s *server) trashedName(username string, ref string) (string, error) {
+	trash, err := s.store.ListTrash(username)
+	if err != nil {
+		return "", err
+	}
+	for _, sheet := range trash {
+		if sheet.ID == ref && ref != "" {
+			return sheet.Name, nil
+		}
+	}
+	for _, sheet := range trash {
+		if sheet.Name == ref {
+			return sheet.Name, nil
+		}
+	}
+	return "", db.ErrNotFound
+}
+
+//Handler loads the trash page
+func (s *server) trashHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Routes back to index if accessed without being logged in yet
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	s.loadTrashPage(w, username, http.StatusOK, "")
+}
+
+//Handler takes a sheet out of the trash, and routes back to the index
+func (s *server) untrashHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	name, err := s.trashedName(username, r.FormValue("sheet"))
+	if err == nil {
+		err = s.store.RestoreSheet(username, name)
+	}
+	if err != nil { //Show the trash again with what went wrong
+		s.loadTrashPage(w, username, errorStatus(err), "Could not restore the sheet: "+err.Error())
+		return
+	}
+	http.Redirect(w, r, "/index/", 303)
+}
+
+//Handler deletes a sheet in the trash for good, and routes back to the trash
+func (s *server) purgeHandler(w http.ResponseWriter, r *http.Request) {
+	username := getUserName(r)
+	if username == "" { //Route to index if the user isnt logged in
+		http.Redirect(w, r, "/index/", 303)
+		return
+	}
+	name, err := s.trashedName(username, r.FormValue("sheet"))
+	if err == nil {
+		err = s.store.PurgeSheet(username, name)
+	}
+	if err != nil {
+		s.loadTrashPage(w, username, errorStatus(err), "Could not purge the sheet: "+err.Error())
+		return
+	}
+	http.Redirect(w, r, "/trash/", 303)
+}
+
+//Loads the trash page, showing what went wrong with the last action if anything
+func (s *server) loadTrashPage(w http.ResponseWriter, username string, status int, message string) {
+	trash, err := s.trashedSheets(username)
+	if err != nil { //Loads error page if we failed to load the trash
+		actionFailed(w, `{"message":"`+err.Error()+`"}`)
+		return
+	}
+	pageData, err := json.Marshal(pages.TrashPage{Sheets: trash, RetentionDays: s.trashDays, Error: message})
+	if err != nil {
+		panic(err)
+	}
+	t, _ := template.ParseFiles("./templates/trash.html")
+	w.WriteHeader(status)
+	t.Execute(w, string(pageData))
+}